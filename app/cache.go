@@ -0,0 +1,324 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	lockTimeout = 10 * time.Second
+	// sessions not used for this long are removed when another session is saved
+	sessionMaxAge = 24 * time.Hour
+)
+
+// sessionFile locates the cached session for a host, port and user
+type sessionFile struct {
+	URL   string    `json:"url"`
+	Host  string    `json:"host"`
+	User  string    `json:"user"`
+	Saved time.Time `json:"saved"`
+	key   string
+}
+
+func sessionDir() string {
+	return strings.Join([]string{configDir(), "sessions"}, pathSep)
+}
+
+func newSessionFile(u *url.URL, user string) sessionFile {
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	host := strings.ToLower(net.JoinHostPort(u.Hostname(), port))
+	user = strings.ToLower(user)
+
+	// readable prefix for operators, hash keeps keys unique after sanitising
+	sum := sha256.Sum256([]byte(host + "\x00" + user))
+	key := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, host+"_"+user)
+
+	return sessionFile{
+		URL:  (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String(),
+		Host: host,
+		User: user,
+		key:  fmt.Sprintf("%v_%v", key, hex.EncodeToString(sum[:4])),
+	}
+}
+
+func (s sessionFile) path(ext string) string {
+	return strings.Join([]string{sessionDir(), s.key + ext}, pathSep)
+}
+
+// read returns the encrypted api and rest cookies, rest is nil for ESXi hosts
+func (s sessionFile) read() (api, rest []byte, err error) {
+	lock, err := getLock(s.path(""), lockTimeout)
+	if err != nil {
+		return
+	}
+	defer func() { _ = lock.Unlock() }()
+
+	api, err = ioutil.ReadFile(s.path(".api"))
+	if err != nil {
+		return
+	}
+	rest, err = ioutil.ReadFile(s.path(".rest"))
+	if os.IsNotExist(err) {
+		return api, nil, nil
+	}
+	return
+}
+
+// write saves encrypted cookies, either may be nil
+func (s sessionFile) write(api, rest []byte) (err error) {
+	err = os.MkdirAll(sessionDir(), 0700)
+	if err != nil {
+		return
+	}
+	lock, err := getLock(s.path(""), lockTimeout)
+	if err != nil {
+		return
+	}
+	defer func() { _ = lock.Unlock() }()
+
+	s.Saved = time.Now()
+	meta, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return
+	}
+	files := []struct {
+		ext string
+		b   []byte
+	}{{".api", api}, {".rest", rest}, {".json", meta}}
+
+	for _, f := range files {
+		if f.b == nil {
+			_ = os.Remove(s.path(f.ext))
+			continue
+		}
+		err = writeFileAtomic(s.path(f.ext), f.b, 0600)
+		if err != nil {
+			return
+		}
+	}
+	return nil
+}
+
+// touch marks a session as in use so cleanup leaves it alone
+func (s sessionFile) touch() {
+	now := time.Now()
+	_ = os.Chtimes(s.path(".json"), now, now)
+}
+
+// remove deletes all files for a session
+func (s sessionFile) remove() (err error) {
+	lock, err := getLock(s.path(""), lockTimeout)
+	if err != nil {
+		return
+	}
+	for _, ext := range []string{".api", ".rest", ".json"} {
+		rmErr := os.Remove(s.path(ext))
+		if rmErr != nil && !os.IsNotExist(rmErr) {
+			err = rmErr
+		}
+	}
+	_ = lock.Unlock()
+	_ = os.Remove(s.path(".lock"))
+	return
+}
+
+// sessionFiles lists cached sessions
+func sessionFiles() (sf []sessionFile, err error) {
+	fi, err := ioutil.ReadDir(sessionDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return
+	}
+	for _, f := range fi {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		b, err := ioutil.ReadFile(strings.Join([]string{sessionDir(), f.Name()}, pathSep))
+		if err != nil {
+			continue
+		}
+		s := sessionFile{}
+		if json.Unmarshal(b, &s) != nil {
+			continue
+		}
+		s.key = strings.TrimSuffix(f.Name(), ".json")
+		// last use rather than creation time
+		s.Saved = f.ModTime()
+		sf = append(sf, s)
+	}
+	return
+}
+
+// cleanSessions removes sessions unused for longer than maxAge
+func cleanSessions(maxAge time.Duration) {
+	sf, err := sessionFiles()
+	if err != nil {
+		return
+	}
+	for _, s := range sf {
+		if time.Since(s.Saved) > maxAge {
+			_ = s.remove()
+		}
+	}
+}
+
+func writeFileAtomic(fn string, b []byte, perm os.FileMode) (err error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(fn), filepath.Base(fn)+".tmp")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	_, err = tmp.Write(b)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+	err = os.Chmod(tmp.Name(), perm)
+	if err != nil {
+		return
+	}
+	return os.Rename(tmp.Name(), fn)
+}
+
+// readLegacySession returns cookies saved by releases that keyed the cache on host only
+func readLegacySession(host string) (api, rest []byte, err error) {
+	api, err = ioutil.ReadFile(strings.Join([]string{configDir(), host + ".api"}, pathSep))
+	if err != nil {
+		return
+	}
+	rest, err = ioutil.ReadFile(strings.Join([]string{configDir(), host + ".rest"}, pathSep))
+	return
+}
+
+func removeLegacySession(host string) {
+	_ = os.Remove(strings.Join([]string{configDir(), host + ".api"}, pathSep))
+	_ = os.Remove(strings.Join([]string{configDir(), host + ".rest"}, pathSep))
+}
+
+// sameUser compares login names, vCenter reports DOMAIN\user for user@domain logins
+func sameUser(a, b string) bool {
+	norm := func(s string) string {
+		s = strings.ToLower(s)
+		if i := strings.Index(s, `\`); i >= 0 {
+			s = s[i+1:] + "@" + s[:i]
+		}
+		return s
+	}
+	return norm(a) == norm(b)
+}
+
+// CachedSession is a session saved to disk by a previous run
+type CachedSession struct {
+	URL      string
+	Host     string
+	User     string
+	LastUsed time.Time
+	sf       sessionFile
+}
+
+// CachedSessions lists sessions saved to disk, optionally limited to a host
+func CachedSessions(host string) (cs []CachedSession, err error) {
+	sf, err := sessionFiles()
+	if err != nil {
+		return
+	}
+	host = strings.ToLower(host)
+	for _, s := range sf {
+		h, _, _ := net.SplitHostPort(s.Host)
+		if host != "" && host != s.Host && host != h {
+			continue
+		}
+		cs = append(cs, CachedSession{URL: s.URL, Host: s.Host, User: s.User, LastUsed: s.Saved, sf: s})
+	}
+	return
+}
+
+// Client loads the cached session, the password is the one used when it was saved
+func (s CachedSession) Client(ctx context.Context, password string, tlsOpts TLSOptions) (c Client, err error) {
+	u, err := url.Parse(s.URL)
+	if err != nil || u.Host == "" {
+		return Client{}, fmt.Errorf("cached url %q invalid", s.URL)
+	}
+	c, err = clientFromDisk(ctx, u, s.User, password, tlsOpts)
+	if err != nil {
+		return Client{}, err
+	}
+	c.Cached = true
+	return
+}
+
+// Verify checks the cached session is still accepted by the server
+func (s CachedSession) Verify(ctx context.Context, password string, tlsOpts TLSOptions) error {
+	c, err := s.Client(ctx, password, tlsOpts)
+	if err != nil {
+		return err
+	}
+	return sessionCheck(ctx, c.c)
+}
+
+// Logout ends the cached session on the server and removes it from disk,
+// files are removed even if the server has already dropped the session
+func (s CachedSession) Logout(ctx context.Context, password string, tlsOpts TLSOptions) error {
+	c, err := s.Client(ctx, password, tlsOpts)
+	if err == nil {
+		c.Cached = false
+		err = c.Logout()
+	}
+	if rmErr := s.Purge(); rmErr != nil {
+		return rmErr
+	}
+	return err
+}
+
+// Purge removes the cached session from disk
+func (s CachedSession) Purge() error {
+	return s.sf.remove()
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewSessionFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b      string
+		userA     string
+		userB     string
+		wantEqual bool
+	}{
+		{"default port", "https://vc1/sdk", "https://VC1:443/sdk", "prtg@vsphere.local", "PRTG@vsphere.local", true},
+		{"other port", "https://vc1/sdk", "https://vc1:8443/sdk", "prtg@vsphere.local", "prtg@vsphere.local", false},
+		{"other user", "https://vc1/sdk", "https://vc1/sdk", "prtg@vsphere.local", "discovery@vsphere.local", false},
+		{"sanitised collision", "https://vc1/sdk", "https://vc1/sdk", `vsphere\prtg`, "vsphere_prtg", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ua, _ := url.Parse(tt.a)
+			ub, _ := url.Parse(tt.b)
+			a, b := newSessionFile(ua, tt.userA), newSessionFile(ub, tt.userB)
+			if (a.key == b.key) != tt.wantEqual {
+				t.Errorf("keys %v %v, want equal %v", a.key, b.key, tt.wantEqual)
+			}
+			if filepath.Base(a.key) != a.key {
+				t.Errorf("key %v is not a plain file name", a.key)
+			}
+		})
+	}
+}
+
+func TestSameUser(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{`VSPHERE.LOCAL\prtg`, "prtg@vsphere.local", true},
+		{"prtg@vsphere.local", "PRTG@VSPHERE.LOCAL", true},
+		{`VSPHERE.LOCAL\discovery`, "prtg@vsphere.local", false},
+	}
+	for _, tt := range tests {
+		if got := sameUser(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameUser(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prtgvmware")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	fn := filepath.Join(dir, "session.api")
+	for _, want := range []string{"first", "second"} {
+		if err := writeFileAtomic(fn, []byte(want), 0600); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("got %v want %v", string(got), want)
+		}
+	}
+	fi, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fi) != 1 {
+		t.Errorf("temporary files left behind %v", len(fi))
+	}
+}
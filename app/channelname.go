@@ -0,0 +1,114 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"hash/fnv"
+	"io/ioutil"
+	"strings"
+	"unicode"
+)
+
+// defaultChannelLength keeps names readable in the PRTG channel table
+const defaultChannelLength = 64
+
+// ChannelNames turns names built from inventory data, I.E guest disk paths and portgroup names,
+// into channel names PRTG keeps history under
+type ChannelNames struct {
+	// Aliases renames channels, keys are the names the summaries build, I.E free Bytes /var
+	Aliases map[string]string `yaml:"aliases"`
+	// MaxLength shortens longer names, defaults to 64
+	MaxLength int `yaml:"maxLength"`
+}
+
+// LoadChannelNames reads aliases and the name length from a yaml file
+func LoadChannelNames(fn string) (*ChannelNames, error) {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, fmt.Errorf("read channel names %v", err)
+	}
+	n := &ChannelNames{}
+	err = yaml.UnmarshalStrict(b, n)
+	if err != nil {
+		return nil, fmt.Errorf("parse channel names %v %v", fn, err)
+	}
+	if n.MaxLength < 0 || n.MaxLength > 0 && n.MaxLength < 16 {
+		return nil, fmt.Errorf("channel names %v maxLength must be at least 16", fn)
+	}
+	return n, nil
+}
+
+// SetChannelNames sets the aliases and length used for channel names
+func (c *Client) SetChannelNames(n *ChannelNames) {
+	c.names = n
+}
+
+// name returns the alias of raw, or raw with characters PRTG can't show removed and long names
+// cut short with a hash of the whole name so different long names stay apart
+func (n *ChannelNames) name(raw string) string {
+	max := defaultChannelLength
+	s := raw
+	if n != nil {
+		if a, ok := n.Aliases[raw]; ok {
+			s = a
+		}
+		if n.MaxLength > 0 {
+			max = n.MaxLength
+		}
+	}
+	s = sanitizeChannel(s)
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(raw))
+	return fmt.Sprintf("%v~%08x", strings.TrimSpace(string(r[:max-9])), h.Sum32())
+}
+
+// sanitizeChannel drops control characters, replaces markup characters and collapses white space
+func sanitizeChannel(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r), unicode.IsSpace(r):
+			return ' '
+		case r == '<', r == '>', r == '"', r == unicode.ReplacementChar:
+			return '_'
+		}
+		return r
+	}, s)
+	s = strings.Join(strings.Fields(s), " ")
+	if s == "" {
+		return "_"
+	}
+	return s
+}
+
+// uniqueChannel returns name, or name with a counter when an earlier channel already has it, I.E two vms of the same name
+func uniqueChannel(name string, taken func(string) bool) string {
+	if !taken(name) {
+		return name
+	}
+	for i := 2; ; i++ {
+		s := fmt.Sprintf("%v (%v)", name, i)
+		if !taken(s) {
+			return s
+		}
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"bytes"
+	ps "github.com/PRTG/go-prtg-sensor-api"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChannelName(t *testing.T) {
+	long := "free Space (Percent) /very/long/mount/point/used/by/a/database/server/data01"
+	long2 := "free Space (Percent) /very/long/mount/point/used/by/a/database/server/data02"
+	aliases := &ChannelNames{Aliases: map[string]string{"free Bytes C:\\": "free Bytes system", "pg\tweb": "web <dmz>"}, MaxLength: 20}
+
+	tests := []struct {
+		name string
+		n    *ChannelNames
+		raw  string
+		want string
+	}{
+		{"plain", nil, "Memory Free (Percent)", "Memory Free (Percent)"},
+		{"windows path kept", nil, `free Bytes C:\`, `free Bytes C:\`},
+		{"control and markup", nil, "pg\t<web>\x00 \"dmz\"", `pg _web_ _dmz_`},
+		{"empty", nil, " \n", "_"},
+		{"alias", aliases, `free Bytes C:\`, "free Bytes system"},
+		{"alias sanitized", aliases, "pg\tweb", "web _dmz_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.n.name(tt.raw); got != tt.want {
+				t.Errorf("name(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+
+	var n *ChannelNames
+	a := n.name(long)
+	if len(a) != defaultChannelLength || !strings.HasPrefix(a, long[:defaultChannelLength-9]+"~") {
+		t.Errorf("long name not shortened %v", a)
+	}
+	if b := n.name(long); a != b {
+		t.Errorf("hash suffix is not stable %v %v", a, b)
+	}
+	if b := n.name(long2); a == b {
+		t.Errorf("long names collide %v %v", a, b)
+	}
+	if s := aliases.name("portgroup production web"); len(s) != 20 || !strings.HasPrefix(s, "portgroup p~") {
+		t.Errorf("maxLength not applied %v", s)
+	}
+}
+
+func TestLoadChannelNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prtgvmware")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	tests := []struct {
+		name, body string
+		wantErr    bool
+	}{
+		{"aliases", "maxLength: 40\naliases:\n  \"free Bytes /\": root free bytes\n", false},
+		{"too short", "maxLength: 8\n", true},
+		{"unknown key", "alias:\n  a: b\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := filepath.Join(dir, tt.name+".yml")
+			_ = ioutil.WriteFile(fn, []byte(tt.body), 0600)
+			n, err := LoadChannelNames(fn)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadChannelNames() error %v", err)
+			}
+			if err == nil && (n.MaxLength != 40 || n.Aliases["free Bytes /"] != "root free bytes") {
+				t.Errorf("loaded %+v", n)
+			}
+		})
+	}
+	if _, err := LoadChannelNames(filepath.Join(dir, "missing.yml")); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
+
+func TestDuplicateChannels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	p := newPrtgData("snapshots")
+	p.out = buf
+	for _, vm := range []string{"web", "db", "web", "web"} {
+		_ = p.addSample(1, ps.SensorChannel{Channel: vm}, "snapshots older than", vm)
+	}
+	_ = p.add(1, ps.SensorChannel{Channel: "db (2)"})
+	if err := p.print(time.Second, false); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, s := range []string{`"channel":"web (2)"`, `"channel":"web (3)"`, `"channel":"db (2)"`, `"text":"duplicate channel names web, web"`} {
+		if !strings.Contains(out, s) {
+			t.Errorf("print() missing %v\n%v", s, out)
+		}
+	}
+	r := p.result(time.Second)
+	if len(r.Samples) != 5 || r.Samples[3].Instance != "web" || r.Samples[3].Channel != "web (2)" {
+		t.Errorf("result %+v", r.Samples)
+	}
+}
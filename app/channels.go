@@ -0,0 +1,290 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"fmt"
+	ps "github.com/PRTG/go-prtg-sensor-api"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxChannels is the most channels PRTG accepts from one sensor, one of them is the execution time
+const maxChannels = 50
+
+// pageSize is the number of summary channels that fit next to the execution time
+const pageSize = maxChannels - 1
+
+// ChannelLimit picks how a summary with more channels than a PRTG sensor accepts is reduced,
+// without one the first page is reported and the sensor message says how many channels were left out
+type ChannelLimit struct {
+	// Page reports one block of channels in name order, starting at 1
+	Page int
+	// Aggregate replaces the channels of each family, I.E one per portgroup, with their count, min, max and worst value
+	Aggregate bool
+	// Top keeps the Top worst channels of all families, channels without a family are always kept
+	Top int
+}
+
+// Validate checks at most one strategy is chosen
+func (l ChannelLimit) Validate() error {
+	n := 0
+	if l.Page != 0 {
+		n++
+	}
+	if l.Aggregate {
+		n++
+	}
+	if l.Top != 0 {
+		n++
+	}
+	if n > 1 {
+		return fmt.Errorf("use only one of --page, --aggregate or --top")
+	}
+	if l.Page < 0 || l.Top < 0 {
+		return fmt.Errorf("--page and --top need a positive number")
+	}
+	return nil
+}
+
+// SetChannelLimit sets how summaries over the PRTG channel limit are reduced
+func (c *Client) SetChannelLimit(l ChannelLimit) {
+	c.limit = l
+}
+
+// apply reduces items to what fits in a sensor, note tells the reader what was left out
+func (l ChannelLimit) apply(items []ps.SensorChannel, meta map[string]sampleMeta) (out []ps.SensorChannel, note string, err error) {
+	switch {
+	case l.Aggregate && len(items) > pageSize:
+		items, note = aggregate(items, meta)
+	case l.Top > 0 && len(items) > pageSize:
+		items, note = top(items, meta, l.Top)
+	}
+
+	page := l.Page
+	if page == 0 {
+		page = 1
+	}
+	pages := (len(items) + pageSize - 1) / pageSize
+	if pages <= 1 && page == 1 {
+		return items, note, nil
+	}
+	if page > pages {
+		return nil, "", fmt.Errorf("page %v not found, %v channels fill %v pages", page, len(items), pages)
+	}
+	end := page * pageSize
+	if end > len(items) {
+		end = len(items)
+	}
+	note = strings.TrimSpace(fmt.Sprintf("%v page %v of %v", note, page, pages))
+	if l.Page == 0 {
+		note = fmt.Sprintf("%v, %v channels is over the PRTG limit, use --page, --aggregate or --top", note, len(items))
+	}
+	return items[(page-1)*pageSize : end], note, nil
+}
+
+// lowIsBad is true for channels that alarm on low values, I.E free space
+func lowIsBad(c ps.SensorChannel) bool {
+	return c.LimitMinError != "" || c.LimitMinWarning != ""
+}
+
+// worse is true when a is in a worse state than b
+func worse(a, b ps.SensorChannel) bool {
+	av, aerr := strconv.ParseFloat(a.Value, 64)
+	bv, berr := strconv.ParseFloat(b.Value, 64)
+	switch {
+	case aerr != nil:
+		return false
+	case berr != nil:
+		return true
+	case lowIsBad(a):
+		return av < bv
+	}
+	return av > bv
+}
+
+// families splits items into channels without a family and members of each family
+func families(items []ps.SensorChannel, meta map[string]sampleMeta) (plain []ps.SensorChannel, fams map[string][]ps.SensorChannel, names []string) {
+	fams = make(map[string][]ps.SensorChannel)
+	for _, c := range items {
+		f := meta[c.Channel].family
+		if f == "" {
+			plain = append(plain, c)
+			continue
+		}
+		if _, ok := fams[f]; !ok {
+			names = append(names, f)
+		}
+		fams[f] = append(fams[f], c)
+	}
+	sort.Strings(names)
+	return
+}
+
+// noLimits copies c without the limits so only one channel of an aggregate alarms
+func noLimits(c ps.SensorChannel) ps.SensorChannel {
+	c.LimitMinWarning, c.LimitMaxWarning, c.LimitWarningMsg = "", "", ""
+	c.LimitMinError, c.LimitMaxError, c.LimitErrorMsg = "", "", ""
+	c.LimitMode, c.Warning = "", ""
+	return c
+}
+
+func aggregate(items []ps.SensorChannel, meta map[string]sampleMeta) ([]ps.SensorChannel, string) {
+	out, fams, names := families(items, meta)
+	worst := make([]string, 0, len(names))
+	for _, f := range names {
+		members := fams[f]
+		if len(members) == 1 {
+			out = append(out, members...)
+			continue
+		}
+		var lo, hi, w ps.SensorChannel
+		var lv, hv float64
+		found := false
+		for _, m := range members {
+			v, err := strconv.ParseFloat(m.Value, 64)
+			if err != nil {
+				continue
+			}
+			if !found || v < lv {
+				lo, lv = m, v
+			}
+			if !found || v > hv {
+				hi, hv = m, v
+			}
+			if !found || worse(m, w) {
+				w = m
+			}
+			found = true
+		}
+		count := ps.SensorChannel{Channel: f + " count", Unit: "Count", Value: strconv.Itoa(len(members))}
+		out = append(out, count)
+		if !found {
+			continue
+		}
+		lo, hi = noLimits(lo), noLimits(hi)
+		lo.Channel, hi.Channel = f+" min", f+" max"
+		worst = append(worst, fmt.Sprintf("worst %v %v", f, meta[w.Channel].instanceOr(w.Channel)))
+		w.Channel = f + " worst"
+		out = append(out, lo, hi, w)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Channel < out[j].Channel
+	})
+	return out, strings.Join(worst, ", ")
+}
+
+func top(items []ps.SensorChannel, meta map[string]sampleMeta, n int) ([]ps.SensorChannel, string) {
+	out, fams, names := families(items, meta)
+	members := make([]ps.SensorChannel, 0, len(items))
+	for _, f := range names {
+		members = append(members, fams[f]...)
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return worse(members[i], members[j])
+	})
+	if room := pageSize - len(out); n > room {
+		n = room
+	}
+	if n < 0 {
+		n = 0
+	}
+	if n > len(members) {
+		n = len(members)
+	}
+	out = append(out, members[:n]...)
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Channel < out[j].Channel
+	})
+	return out, fmt.Sprintf("worst %v of %v", n, len(members))
+}
+
+// instanceOr returns the instance a channel was added for, or def for channels without one
+func (m sampleMeta) instanceOr(def string) string {
+	if m.instance != "" {
+		return m.instance
+	}
+	return def
+}
+
+// pages returns how many sensors are needed to report every channel of an object
+func (c *Client) pages(ctx context.Context, kind, moid string, opts CollectOptions) (int, error) {
+	var n int
+	oc := *c
+	oc.SetSink(func(r Result) error {
+		n = len(r.Samples)
+		return nil
+	})
+	err := oc.summary(ctx, kind, moid, opts)
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 1, nil
+	}
+	return (n + pageSize - 1) / pageSize, nil
+}
+
+// pageItems splits the sensors of objects with more channels than PRTG accepts into one sensor per page,
+// only distributed switches are checked, they gain a channel per portgroup
+func (c *Client) pageItems(ctx context.Context, items []Item, moidMap *moidNames) ([]Item, error) {
+	out := make([]Item, 0, len(items))
+	for _, v := range items {
+		if moidMap.Gettype(v.ID) != "VmwareDistributedVirtualSwitch" {
+			out = append(out, v)
+			continue
+		}
+		n, err := c.pages(ctx, moidMap.Gettype(v.ID), v.ID, CollectOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("count channels of %v %v", v.Name, err)
+		}
+		out = append(out, pageItem(v, n)...)
+	}
+	return out, nil
+}
+
+func pageItem(v Item, n int) []Item {
+	if n <= 1 {
+		return []Item{v}
+	}
+	out := make([]Item, 0, n)
+	for i := 1; i <= n; i++ {
+		p := v
+		p.Name = fmt.Sprintf("%v page %v", v.Name, i)
+		if p.Displayname != "" {
+			p.Displayname = p.Name
+		}
+		p.Params = fmt.Sprintf("%v --page %v", v.Params, i)
+		out = append(out, p)
+	}
+	return out
+}
+
+// snapshotPages returns how many snapshot sensors are needed for the vms they report, every vm without tags
+func snapshotPages(tm *TagMap, moidMap *moidNames, tags []string) int {
+	moidMap.mu.RLock()
+	defer moidMap.mu.RUnlock()
+	n := 0
+	for id, o := range moidMap.moid {
+		if o.vmwareType == "VirtualMachine" && (len(tags) == 0 || tm.check(id, tags)) {
+			n++
+		}
+	}
+	return (n + pageSize - 1) / pageSize
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"bytes"
+	"fmt"
+	ps "github.com/PRTG/go-prtg-sensor-api"
+	"strings"
+	"testing"
+	"time"
+)
+
+// portgroups builds a summary with two plain channels and n portgroup channels, pg-7 is the worst
+func portgroups(n int) ([]ps.SensorChannel, map[string]sampleMeta) {
+	items := []ps.SensorChannel{{Channel: "Config Status", Value: "0"}, {Channel: "Overall Status", Value: "0"}}
+	meta := make(map[string]sampleMeta)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("pg-%02d", i)
+		v := "0"
+		if i == 7 {
+			v = "2"
+		} else if i%10 == 3 {
+			v = "1"
+		}
+		items = append(items, ps.SensorChannel{Channel: name, Value: v, ValueLookup: lookupStatus})
+		meta[name] = sampleMeta{family: "portgroup status", instance: name}
+	}
+	return items, meta
+}
+
+func channelNames(c []ps.SensorChannel) string {
+	n := make([]string, 0, len(c))
+	for _, v := range c {
+		n = append(n, v.Channel+"="+v.Value)
+	}
+	return strings.Join(n, " ")
+}
+
+func TestChannelLimit(t *testing.T) {
+	small, smallMeta := portgroups(10)
+	big, bigMeta := portgroups(90)
+
+	tests := []struct {
+		name      string
+		l         ChannelLimit
+		items     []ps.SensorChannel
+		meta      map[string]sampleMeta
+		wantLen   int
+		wantFirst string
+		wantNote  string
+		wantErr   bool
+	}{
+		{"under limit untouched", ChannelLimit{Aggregate: true}, small, smallMeta, 12, "Config Status", "", false},
+		{"default first page", ChannelLimit{}, big, bigMeta, 49, "Config Status", "page 1 of 2, 92 channels is over the PRTG limit", false},
+		{"page 2", ChannelLimit{Page: 2}, big, bigMeta, 43, "pg-47", "page 2 of 2", false},
+		{"missing page", ChannelLimit{Page: 3}, big, bigMeta, 0, "", "", true},
+		{"page 1 under limit", ChannelLimit{Page: 1}, small, smallMeta, 12, "Config Status", "", false},
+		{"aggregate", ChannelLimit{Aggregate: true}, big, bigMeta, 6, "Config Status", "worst portgroup status pg-07", false},
+		{"top", ChannelLimit{Top: 5}, big, bigMeta, 7, "Config Status", "worst 5 of 90", false},
+		{"top larger than fits", ChannelLimit{Top: 80}, big, bigMeta, 49, "Config Status", "worst 47 of 90", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, note, err := tt.l.apply(tt.items, tt.meta)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("apply() error %v", err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != tt.wantLen || got[0].Channel != tt.wantFirst || !strings.Contains(note, tt.wantNote) {
+				t.Errorf("apply() = %v channels, note %q\n%v", len(got), note, channelNames(got))
+			}
+		})
+	}
+
+	agg, _, _ := ChannelLimit{Aggregate: true}.apply(big, bigMeta)
+	if s := channelNames(agg); s != "Config Status=0 Overall Status=0 portgroup status count=90 portgroup status max=2 portgroup status min=0 portgroup status worst=2" {
+		t.Errorf("aggregate channels %v", s)
+	}
+	tp, _, _ := ChannelLimit{Top: 3}.apply(big, bigMeta)
+	if s := channelNames(tp); !strings.Contains(s, "pg-07=2") || strings.Count(s, "=1") != 2 {
+		t.Errorf("top channels %v", s)
+	}
+
+	free := []ps.SensorChannel{}
+	freeMeta := make(map[string]sampleMeta)
+	for i := 0; i < 60; i++ {
+		name := fmt.Sprintf("free %02d", i)
+		free = append(free, ps.SensorChannel{Channel: name, Value: fmt.Sprint(50 + i), LimitMinWarning: "10"})
+		freeMeta[name] = sampleMeta{family: "guest disk free percent", instance: name}
+	}
+	agg, note, _ := ChannelLimit{Aggregate: true}.apply(free, freeMeta)
+	if s := channelNames(agg); !strings.Contains(s, "guest disk free percent worst=50") || agg[1].LimitMinWarning != "" || agg[3].LimitMinWarning != "10" {
+		t.Errorf("low values are worst for free space %v %q %+v", s, note, agg)
+	}
+
+	for _, l := range []ChannelLimit{{Page: 1, Top: 2}, {Aggregate: true, Page: 2}, {Top: -1}} {
+		if l.Validate() == nil {
+			t.Errorf("Validate(%+v) expected error", l)
+		}
+	}
+}
+
+func TestPrtgDataChannelLimit(t *testing.T) {
+	big, bigMeta := portgroups(90)
+	buf := &bytes.Buffer{}
+	p := newPrtgData("vds")
+	p.out = buf
+	p.text = "switch"
+	p.limit = ChannelLimit{Page: 2}
+	for _, c := range big {
+		m := bigMeta[c.Channel]
+		_ = p.addSample(c.Value, c, m.family, m.instance)
+	}
+	if err := p.print(time.Second, false); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); strings.Count(s, `"channel"`) != 44 || !strings.Contains(s, `"text":"switch page 2 of 2"`) {
+		t.Errorf("print() %v", s)
+	}
+
+	buf.Reset()
+	p.limit = ChannelLimit{Page: 5}
+	if err := p.print(time.Second, false); err == nil || !strings.Contains(buf.String(), `"error":"1"`) {
+		t.Errorf("missing page should be a sensor error %v %v", err, buf)
+	}
+}
+
+func TestPageTemplates(t *testing.T) {
+	items := pageItem(Item{Name: "VDS sw", ID: "dvs-1", Params: "vdsSummary --oid dvs-1"}, 2)
+	if len(items) != 2 || items[1].Name != "VDS sw page 2" || items[1].Params != "vdsSummary --oid dvs-1 --page 2" {
+		t.Errorf("pageItem() %+v", items)
+	}
+	d := NewDeviceTemplate(time.Hour, "prtg", "")
+	d.pageSnapshots(3)
+	n := 0
+	for _, c := range d.Create {
+		if strings.HasPrefix(c.ID, "snapshots") {
+			n++
+			if !strings.HasSuffix(c.Createdata.Exeparams, fmt.Sprintf("--page %v", n)) {
+				t.Errorf("snapshot page %v params %v", n, c.Createdata.Exeparams)
+			}
+		}
+	}
+	if n != 3 || len(d.Create) != 5 {
+		t.Errorf("pageSnapshots() %+v", d.Create)
+	}
+}
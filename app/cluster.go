@@ -0,0 +1,148 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"fmt"
+	ps "github.com/PRTG/go-prtg-sensor-api"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"time"
+)
+
+// clusterSummaryDefault are the cluster counters read, clusters only keep historical stats
+var clusterSummaryDefault = []string{"cpu.usage.average", "mem.usage.average"}
+
+// ClusterSummary  stats for a cluster
+func (c *Client) ClusterSummary(ctx context.Context, name, moid string, js bool) (err error) {
+	start := time.Now()
+	ictx, cancel := c.inventoryCtx(ctx)
+	defer cancel()
+
+	id := types.ManagedObjectReference{
+		Type:  "ClusterComputeResource",
+		Value: moid,
+	}
+	if moid == "" {
+		id, err = c.findOne(ictx, name, id.Type)
+		if err != nil {
+			return PhaseError(ictx, "inventory", err)
+		}
+	}
+	cl := mo.ClusterComputeResource{}
+	err = c.retrieveOne(ictx, id, []string{"name", "summary", "configurationEx", "host", "overallStatus", "triggeredAlarmState"}, &cl)
+	if err != nil {
+		return PhaseError(ictx, "inventory", errCheck(name, id, fmt.Errorf("cluster v.properties %v", err)))
+	}
+	var hosts []mo.HostSystem
+	err = c.retrieve(ictx, cl.Host, []string{"name", "runtime.connectionState", "runtime.inMaintenanceMode"}, &hosts)
+	if err != nil {
+		return PhaseError(ictx, "inventory", fmt.Errorf("cluster hosts %v", err))
+	}
+
+	pr := c.prtgData(id, cl.Name)
+	_ = pr.add(managedEntityStatus(cl.OverallStatus), ps.SensorChannel{Channel: "Overall Status", Unit: "Custom", CustomUnit: "Custom", ValueLookup: lookupStatus})
+	_ = pr.add(triggeredAlarms(cl.TriggeredAlarmState), ps.SensorChannel{Channel: "Triggered Alarms", Unit: "Count", LimitMaxWarning: "1", LimitWarningMsg: "triggered alarms present"})
+
+	var connected, maintenance, disconnected int
+	for _, h := range hosts {
+		switch {
+		case h.Runtime.ConnectionState != types.HostSystemConnectionStateConnected:
+			disconnected++
+			pr.failures = append(pr.failures, h.Name)
+		case h.Runtime.InMaintenanceMode:
+			maintenance++
+		default:
+			connected++
+		}
+	}
+	_ = pr.add(len(hosts), ps.SensorChannel{Channel: "Hosts", Unit: "Count"})
+	_ = pr.add(connected, ps.SensorChannel{Channel: "Hosts Connected", Unit: "Count"})
+	_ = pr.add(maintenance, ps.SensorChannel{Channel: "Hosts In Maintenance", Unit: "Count", LimitMaxWarning: "0", LimitWarningMsg: "hosts in maintenance mode", LimitMode: "1"})
+	_ = pr.add(disconnected, ps.SensorChannel{Channel: "Hosts Disconnected", Unit: "Count", LimitMaxError: "0", LimitErrorMsg: "hosts disconnected or not responding", LimitMode: "1"})
+	if disconnected > 0 {
+		pr.text = fmt.Sprintf("%v of %v hosts disconnected", disconnected, len(hosts))
+	}
+
+	if s, ok := cl.Summary.(*types.ClusterComputeResourceSummary); ok {
+		clusterCapacity(pr, s)
+	}
+	if cfg, ok := cl.ConfigurationEx.(*types.ClusterConfigInfoEx); ok {
+		clusterConfig(pr, cfg)
+	}
+	elapsed := time.Since(start)
+
+	err = c.Metrics(ctx, id, pr, clusterSummaryDefault, historicalPerfInterval)
+	if err != nil {
+		return err
+	}
+	_ = pr.print(elapsed, js)
+	return nil
+}
+
+// clusterCapacity adds effective and total resources and the failover capacity kept by HA admission control
+func clusterCapacity(pr *prtgData, s *types.ClusterComputeResourceSummary) {
+	_ = pr.add(s.TotalCpu, ps.SensorChannel{Channel: "CPU Total MHz", Unit: "Custom", VolumeSize: "One", CustomUnit: "MHz"})
+	_ = pr.add(s.EffectiveCpu, ps.SensorChannel{Channel: "CPU Effective MHz", Unit: "Custom", VolumeSize: "One", CustomUnit: "MHz"})
+	_ = pr.add(s.TotalMemory, ps.SensorChannel{Channel: "Memory Total", Unit: "BytesMemory"})
+	_ = pr.add(s.EffectiveMemory*1024*1024, ps.SensorChannel{Channel: "Memory Effective", Unit: "BytesMemory"})
+	if s.TotalCpu > 0 {
+		_ = pr.add(int64(s.EffectiveCpu)*100/int64(s.TotalCpu), ps.SensorChannel{Channel: "CPU Effective (Percent)", Unit: "Percent"})
+	}
+	if s.TotalMemory > 0 {
+		_ = pr.add(s.EffectiveMemory*1024*1024*100/s.TotalMemory, ps.SensorChannel{Channel: "Memory Effective (Percent)", Unit: "Percent"})
+	}
+	_ = pr.add(s.NumEffectiveHosts, ps.SensorChannel{Channel: "Hosts Effective", Unit: "Count"})
+
+	switch ac := s.AdmissionControlInfo.(type) {
+	case *types.ClusterFailoverResourcesAdmissionControlInfo:
+		_ = pr.add(ac.CurrentCpuFailoverResourcesPercent, ps.SensorChannel{Channel: "Failover CPU Capacity (Percent)", Unit: "Percent"})
+		_ = pr.add(ac.CurrentMemoryFailoverResourcesPercent, ps.SensorChannel{Channel: "Failover Memory Capacity (Percent)", Unit: "Percent"})
+	case *types.ClusterFailoverLevelAdmissionControlInfo:
+		_ = pr.add(ac.CurrentFailoverLevel, ps.SensorChannel{Channel: "Failover Level", Unit: "Count"})
+	case *types.ClusterFailoverHostAdmissionControlInfo:
+		ok := 0
+		for _, h := range ac.HostStatus {
+			if h.Status == types.ManagedEntityStatusGreen {
+				ok++
+			}
+		}
+		_ = pr.add(ok, ps.SensorChannel{Channel: "Failover Hosts Available", Unit: "Count"})
+	}
+}
+
+// clusterConfig adds whether HA and DRS are enabled and how they are set up
+func clusterConfig(pr *prtgData, cfg *types.ClusterConfigInfoEx) {
+	das := cfg.DasConfig
+	_ = pr.add(boolToInt(isTrue(das.Enabled)), ps.SensorChannel{Channel: "HA Enabled", Unit: "Custom", ValueLookup: lookupEnabled})
+	if isTrue(das.Enabled) {
+		_ = pr.add(boolToInt(isTrue(das.AdmissionControlEnabled)), ps.SensorChannel{Channel: "HA Admission Control", Unit: "Custom", ValueLookup: lookupEnabled})
+		_ = pr.add(boolToInt(das.HostMonitoring != string(types.ClusterDasConfigInfoServiceStateDisabled)), ps.SensorChannel{Channel: "HA Host Monitoring", Unit: "Custom", ValueLookup: lookupEnabled})
+		_ = pr.add(vmMonitoring(das.VmMonitoring), ps.SensorChannel{Channel: "HA VM Monitoring", Unit: "Custom", ValueLookup: lookupVMMonitoring})
+	}
+
+	drs := cfg.DrsConfig
+	_ = pr.add(boolToInt(isTrue(drs.Enabled)), ps.SensorChannel{Channel: "DRS Enabled", Unit: "Custom", ValueLookup: lookupEnabled})
+	if isTrue(drs.Enabled) {
+		_ = pr.add(drsBehavior(drs.DefaultVmBehavior), ps.SensorChannel{Channel: "DRS Automation Level", Unit: "Custom", ValueLookup: lookupDrsBehavior})
+	}
+}
+
+func isTrue(b *bool) bool {
+	return b != nil && *b
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"testing"
+	"time"
+)
+
+func TestClusterSummary(t *testing.T) {
+	c, stop := newSimClient(t, func(m *simulator.Model) {
+		m.Host = 0
+		m.ClusterHost = 3
+	})
+	defer stop()
+	ctx := context.Background()
+
+	// one host in maintenance and one disconnected
+	hosts := simulator.Map.All("HostSystem")
+	simulator.Map.Get(hosts[0].Reference()).(*simulator.HostSystem).Runtime.InMaintenanceMode = true
+	h1 := simulator.Map.Get(hosts[1].Reference()).(*simulator.HostSystem)
+	h1.Runtime.ConnectionState = types.HostSystemConnectionStateDisconnected
+
+	var got Result
+	c.SetSink(func(r Result) error {
+		got = r
+		return nil
+	})
+	err := c.ClusterSummary(ctx, "DC0_C0", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"Hosts":                "3",
+		"Hosts Connected":      "1",
+		"Hosts In Maintenance": "1",
+		"Hosts Disconnected":   "1",
+		"DRS Enabled":          "1",
+		"DRS Automation Level": "0",
+		"HA Enabled":           "0",
+		"Overall Status":       "0",
+	}
+	values := make(map[string]string)
+	for _, s := range got.Samples {
+		values[s.Channel] = s.Value
+	}
+	for ch, v := range want {
+		if values[ch] != v {
+			t.Errorf("%v = %q, want %v", ch, values[ch], v)
+		}
+	}
+	if got.Type != "ClusterComputeResource" || got.Text != "disconnected "+h1.Name+", Hosts Disconnected: 1, HA Enabled: Disabled, Hosts In Maintenance: 1" {
+		t.Errorf("result %v %q", got.Type, got.Text)
+	}
+
+	// clusters are found by folder and get a sensor of their own
+	tm := NewTagMap()
+	if err := c.discover(ctx, Selection{Folders: []string{"/DC0/host"}}, tm); err != nil {
+		t.Fatal(err)
+	}
+	names, err := newMoidNames(ctx, &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := c.obMeta(tm, names, time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cl mo.ClusterComputeResource
+	ref := simulator.Map.Any("ClusterComputeResource").Reference()
+	if err := c.retrieveOne(ctx, ref, []string{"name"}, &cl); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, it := range meta.Items {
+		found = found || (it.ID == ref.Value && it.Name == "Cluster "+cl.Name)
+	}
+	if !found {
+		t.Errorf("no cluster sensor in %+v", meta.Items)
+	}
+}
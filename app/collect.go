@@ -0,0 +1,155 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CollectOptions are the sensor settings used for every object during collection
+type CollectOptions struct {
+	SnapAge   time.Duration
+	VMMetrics []string
+	Limits    LimitsStruct
+	// Workers is the number of objects collected at once, defaults to 4
+	Workers int
+}
+
+// collectTypes are the object types with a summary, in the order they are collected
+var collectTypes = []string{"ClusterComputeResource", "HostSystem", "StoragePod", "Datastore", "VmwareDistributedVirtualSwitch", "DistributedVirtualPortgroup", "ResourcePool", "VirtualMachine"}
+
+// Collect runs the summary of every selected object and hands each result to f, one at a time,
+// objects that fail are passed on with Err set so writers can report them
+func (c *Client) Collect(ctx context.Context, sel Selection, opts CollectOptions, f func(Result) error) error {
+	ictx, cancel := c.inventoryCtx(ctx)
+	tm := NewTagMap()
+	err := c.discover(ictx, sel, tm)
+	if err != nil {
+		cancel()
+		return PhaseError(ictx, "inventory", err)
+	}
+	names, err := newMoidNames(ictx, c)
+	if err != nil {
+		cancel()
+		return PhaseError(ictx, "inventory", err)
+	}
+	cancel()
+
+	type object struct {
+		id, kind string
+		tags     []string
+	}
+	objs := make([]object, 0, len(tm.Data))
+	for id, d := range tm.Data {
+		kind := names.Gettype(id)
+		// objects listed by a manifest may have been removed since it was written
+		if kind == "" || inStringSlice(kind, collectTypes) {
+			objs = append(objs, object{id: id, kind: kind, tags: d.Tags})
+		}
+	}
+	order := func(kind string) int {
+		for i, t := range collectTypes {
+			if t == kind {
+				return i
+			}
+		}
+		return len(collectTypes)
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		if objs[i].kind != objs[j].kind {
+			return order(objs[i].kind) < order(objs[j].kind)
+		}
+		return objs[i].id < objs[j].id
+	})
+
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 4
+	}
+	var mu sync.Mutex
+	var firstErr error
+	sem := make(chan struct{}, workers)
+	wg := sync.WaitGroup{}
+	for _, o := range objs {
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(o object) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			sent := false
+			var ferr error
+			oc := *c
+			oc.SetSink(func(r Result) error {
+				sent = true
+				r.Tags = o.tags
+				mu.Lock()
+				defer mu.Unlock()
+				ferr = f(r)
+				return ferr
+			})
+			err := fmt.Errorf("object %v not found", o.id)
+			if o.kind != "" {
+				err = oc.summary(ctx, o.kind, o.id, opts)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && !sent {
+				ferr = f(Result{Type: o.kind, Moid: o.id, Name: names.GetName(o.id), Tags: o.tags, Err: err.Error(), Time: time.Now()})
+			}
+			if ferr != nil && firstErr == nil {
+				firstErr = ferr
+			}
+		}(o)
+	}
+	wg.Wait()
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = fmt.Errorf("collection stopped %v", ctx.Err())
+	}
+	return firstErr
+}
+
+// summary runs the summary sensor matching kind for a single object
+func (c *Client) summary(ctx context.Context, kind, moid string, opts CollectOptions) error {
+	lim := opts.Limits
+	switch kind {
+	case "VirtualMachine":
+		return c.VMSummary(ctx, "", moid, &lim, opts.SnapAge, false, opts.VMMetrics)
+	case "HostSystem":
+		return c.HostSummary(ctx, "", moid, false)
+	case "Datastore":
+		return c.DsSummary(ctx, "", moid, &lim, false)
+	case "VmwareDistributedVirtualSwitch":
+		return c.VdsSummary(ctx, "", moid, false)
+	case "ClusterComputeResource":
+		return c.ClusterSummary(ctx, "", moid, false)
+	case "ResourcePool":
+		return c.ResourcePoolSummary(ctx, "", moid, false)
+	case "StoragePod":
+		return c.PodSummary(ctx, "", moid, false)
+	case "DistributedVirtualPortgroup":
+		return c.PortgroupSummary(ctx, "", moid, false)
+	}
+	return fmt.Errorf("no summary for %v", kind)
+}
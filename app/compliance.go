@@ -0,0 +1,258 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	ps "github.com/PRTG/go-prtg-sensor-api"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Baseline is the configuration hosts are checked against by hostCompliance, rules left empty are not checked
+type Baseline struct {
+	// Lockdown is the lockdown mode hosts should be in, disabled, normal or strict
+	Lockdown string `yaml:"lockdown"`
+	// Services are host service keys, I.E TSM-SSH, and whether they should be running
+	Services map[string]bool `yaml:"services"`
+	// NTP checks ntp servers are configured and ntpd is running
+	NTP bool `yaml:"ntp"`
+	// Syslog checks Syslog.global.logHost is set
+	Syslog bool `yaml:"syslog"`
+	// Settings are advanced settings and the value they should have
+	Settings map[string]string `yaml:"settings"`
+	// CertificateDays is how many days the host certificate must still be valid for
+	CertificateDays int `yaml:"certificateDays"`
+}
+
+// lockdownModes maps baseline lockdown names to the modes hosts report
+var lockdownModes = map[string]types.HostLockdownMode{
+	"disabled": types.HostLockdownModeLockdownDisabled,
+	"normal":   types.HostLockdownModeLockdownNormal,
+	"strict":   types.HostLockdownModeLockdownStrict,
+}
+
+// syslogSetting is the advanced setting holding the remote syslog targets
+const syslogSetting = "Syslog.global.logHost"
+
+// LoadBaseline reads a yaml baseline file
+func LoadBaseline(fn string) (b Baseline, err error) {
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return b, fmt.Errorf("read baseline %v", err)
+	}
+	err = yaml.UnmarshalStrict(buf, &b)
+	if err != nil {
+		return b, fmt.Errorf("parse baseline %v %v", fn, err)
+	}
+	if _, ok := lockdownModes[b.Lockdown]; b.Lockdown != "" && !ok {
+		return b, fmt.Errorf("baseline %v lockdown %q should be disabled, normal or strict", fn, b.Lockdown)
+	}
+	if b.rules() == 0 {
+		return b, fmt.Errorf("baseline %v has no rules", fn)
+	}
+	return b, nil
+}
+
+// rules is the number of rules in the baseline
+func (b Baseline) rules() int {
+	n := len(b.Services) + len(b.Settings)
+	// ntp checks both the servers and the service
+	for _, set := range []bool{b.Lockdown != "", b.NTP, b.NTP, b.Syslog, b.CertificateDays > 0} {
+		n += boolToInt(set)
+	}
+	return n
+}
+
+// hostState is what a host is checked against the baseline with
+type hostState struct {
+	lockdown types.HostLockdownMode
+	services map[string]bool
+	ntp      []string
+	settings map[string]string
+	cert     *x509.Certificate
+}
+
+// HostCompliance checks a host against the baseline, every rule gets a channel that is 1 when the host complies
+func (c *Client) HostCompliance(ctx context.Context, name, moid string, b Baseline, js bool) (err error) {
+	start := time.Now()
+	ictx, cancel := c.inventoryCtx(ctx)
+	defer cancel()
+
+	id := types.ManagedObjectReference{
+		Type:  "HostSystem",
+		Value: moid,
+	}
+	if moid == "" {
+		id, err = c.findOne(ictx, name, id.Type)
+		if err != nil {
+			return PhaseError(ictx, "inventory", err)
+		}
+	}
+	hs := mo.HostSystem{}
+	err = c.retrieveOne(ictx, id, []string{"name", "parent", "configManager", "config.lockdownMode", "config.adminDisabled", "config.certificate"}, &hs)
+	if err != nil {
+		return PhaseError(ictx, "inventory", errCheck(name, id, fmt.Errorf("hs v.properties %v", err)))
+	}
+	st, err := c.hostState(ictx, hs, b)
+	if err != nil {
+		return PhaseError(ictx, "inventory", errCheck(name, id, err))
+	}
+
+	pr := c.prtgData(types.ManagedObjectReference{Type: "compliance", Value: id.Value}, hs.Name)
+	pr.host, pr.cluster = hs.Name, c.clusterName(ictx, hs.Parent)
+	passed := 0
+	rule := func(channel string, ok bool) {
+		passed += boolToInt(ok)
+		if !ok {
+			pr.failures = append(pr.failures, channel)
+		}
+		_ = pr.addSample(boolToInt(ok), ps.SensorChannel{Channel: channel, Unit: "Custom", VolumeSize: "Custom", ValueLookup: lookupCompliance}, "rule", channel)
+	}
+
+	if b.Lockdown != "" {
+		rule("Lockdown Mode", st.lockdown == lockdownModes[b.Lockdown])
+	}
+	services := make([]string, 0, len(b.Services))
+	for k := range b.Services {
+		services = append(services, k)
+	}
+	sort.Strings(services)
+	for _, k := range services {
+		rule("Service "+k, st.services[k] == b.Services[k])
+	}
+	if b.NTP {
+		rule("NTP Configured", len(st.ntp) > 0)
+		rule("NTP Running", st.services["ntpd"])
+	}
+	if b.Syslog {
+		rule("Syslog Target", st.settings[syslogSetting] != "")
+	}
+	settings := make([]string, 0, len(b.Settings))
+	for k := range b.Settings {
+		settings = append(settings, k)
+	}
+	sort.Strings(settings)
+	for _, k := range settings {
+		v, ok := st.settings[k]
+		rule("Setting "+k, ok && v == b.Settings[k])
+	}
+	if b.CertificateDays > 0 {
+		valid := false
+		if st.cert != nil {
+			left := time.Until(st.cert.NotAfter)
+			valid = time.Now().After(st.cert.NotBefore) && left >= time.Duration(b.CertificateDays)*24*time.Hour
+			_ = pr.add(int(left.Hours()/24), ps.SensorChannel{Channel: "Certificate Days Left", Unit: "Custom", CustomUnit: "days"})
+		}
+		rule("Certificate Valid", valid)
+	}
+
+	total := b.rules()
+	_ = pr.add(float64(passed)*100/float64(total), ps.SensorChannel{Channel: "Compliance (Percent)", Unit: "Percent", LimitMinWarning: "100", LimitWarningMsg: "host differs from the baseline", LimitMode: "1"})
+	if len(pr.failures) > 0 {
+		pr.text = fmt.Sprintf("%v of %v rules failing %v", len(pr.failures), total, strings.Join(pr.failures, ", "))
+	}
+	_ = pr.print(time.Since(start), js)
+	return nil
+}
+
+// hostState reads what the baseline needs from the host service, date time and option managers,
+// managers the host doesn't have leave their part empty so the rules using them fail
+func (c *Client) hostState(ctx context.Context, hs mo.HostSystem, b Baseline) (st hostState, err error) {
+	st.services = make(map[string]bool)
+	st.settings = make(map[string]string)
+	if hs.Config != nil {
+		st.lockdown = hs.Config.LockdownMode
+		// hosts older than 6.0 only report whether lockdown is on
+		if st.lockdown == "" {
+			st.lockdown = types.HostLockdownModeLockdownDisabled
+			if hs.Config.AdminDisabled != nil && *hs.Config.AdminDisabled {
+				st.lockdown = types.HostLockdownModeLockdownNormal
+			}
+		}
+		if block, _ := pem.Decode(hs.Config.Certificate); block != nil {
+			st.cert, _ = x509.ParseCertificate(block.Bytes)
+		}
+	}
+
+	cm := hs.ConfigManager
+	if (len(b.Services) > 0 || b.NTP) && cm.ServiceSystem != nil {
+		var ss mo.HostServiceSystem
+		err = c.retrieveOne(ctx, *cm.ServiceSystem, []string{"serviceInfo"}, &ss)
+		if err != nil {
+			return st, fmt.Errorf("host services %v", err)
+		}
+		for _, s := range ss.ServiceInfo.Service {
+			st.services[s.Key] = s.Running
+		}
+	}
+	if b.NTP && cm.DateTimeSystem != nil {
+		var dt mo.HostDateTimeSystem
+		err = c.retrieveOne(ctx, *cm.DateTimeSystem, []string{"dateTimeInfo"}, &dt)
+		if err != nil {
+			return st, fmt.Errorf("host date time %v", err)
+		}
+		if dt.DateTimeInfo.NtpConfig != nil {
+			st.ntp = dt.DateTimeInfo.NtpConfig.Server
+		}
+	}
+
+	keys := make([]string, 0, len(b.Settings)+1)
+	for k := range b.Settings {
+		keys = append(keys, k)
+	}
+	if b.Syslog {
+		keys = append(keys, syslogSetting)
+	}
+	if len(keys) > 0 && cm.AdvancedOption != nil {
+		for _, k := range keys {
+			var opts []types.BaseOptionValue
+			err = c.retry(ctx, func() (err error) {
+				opts, err = object.NewOptionManager(c.c, *cm.AdvancedOption).Query(ctx, k)
+				return err
+			})
+			// unknown settings fail their rule rather than the sensor
+			if err != nil && !isInvalidName(err) {
+				return st, fmt.Errorf("host setting %v %v", k, err)
+			}
+			for _, o := range opts {
+				if v := o.GetOptionValue(); v.Key == k {
+					st.settings[k] = fmt.Sprint(v.Value)
+				}
+			}
+		}
+	}
+	return st, nil
+}
+
+// isInvalidName is the fault hosts answer queries for settings they don't have with
+func isInvalidName(err error) bool {
+	switch faultOf(err).(type) {
+	case types.InvalidName, *types.InvalidName:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"encoding/pem"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadBaseline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prtgvmware")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	tests := []struct {
+		name    string
+		yaml    string
+		rules   int
+		wantErr string
+	}{
+		{"full", "lockdown: normal\nservices:\n  TSM-SSH: false\nntp: true\nsyslog: true\nsettings:\n  UserVars.SuppressShellWarning: \"0\"\ncertificateDays: 30\n", 7, ""},
+		{"services only", "services:\n  TSM: false\n  TSM-SSH: false\n", 2, ""},
+		{"bad lockdown", "lockdown: on\n", 0, "should be disabled, normal or strict"},
+		{"unknown rule", "ssh: false\n", 0, "parse baseline"},
+		{"empty", "lockdown: \"\"\n", 0, "no rules"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := filepath.Join(dir, "baseline.yml")
+			if err := ioutil.WriteFile(fn, []byte(tt.yaml), 0600); err != nil {
+				t.Fatal(err)
+			}
+			b, err := LoadBaseline(fn)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadBaseline() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.rules() != tt.rules {
+				t.Errorf("rules() = %v, want %v", b.rules(), tt.rules)
+			}
+		})
+	}
+	if _, err := LoadBaseline(filepath.Join(dir, "missing.yml")); err == nil {
+		t.Errorf("no error for a missing baseline")
+	}
+}
+
+func TestHostCompliance(t *testing.T) {
+	c, stop := newSimClient(t, nil)
+	defer stop()
+	ctx := context.Background()
+
+	// the simulator has no service or date time system, stand ins answer for the host
+	hs := simulator.Map.Get(simulator.Map.Any("HostSystem").Reference()).(*simulator.HostSystem)
+	simulator.Map.Put(&mo.HostServiceSystem{
+		ExtensibleManagedObject: mo.ExtensibleManagedObject{Self: *hs.ConfigManager.ServiceSystem},
+		ServiceInfo: types.HostServiceInfo{Service: []types.HostService{
+			{Key: "TSM-SSH", Running: true}, {Key: "TSM", Running: false}, {Key: "ntpd", Running: true},
+		}},
+	})
+	simulator.Map.Put(&mo.HostDateTimeSystem{
+		Self:         *hs.ConfigManager.DateTimeSystem,
+		DateTimeInfo: types.HostDateTimeInfo{NtpConfig: &types.HostNtpConfig{Server: []string{"pool.ntp.org"}}},
+	})
+	simulator.Map.Get(*hs.ConfigManager.AdvancedOption).(*simulator.OptionManager).Setting = []types.BaseOptionValue{
+		&types.OptionValue{Key: "UserVars.SuppressShellWarning", Value: int64(1)},
+		&types.OptionValue{Key: syslogSetting, Value: "udp://syslog.local:514"},
+	}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Close()
+	hs.Config.LockdownMode = types.HostLockdownModeLockdownNormal
+	hs.Config.Certificate = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+	b := Baseline{
+		Lockdown:        "normal",
+		Services:        map[string]bool{"TSM-SSH": false, "TSM": false},
+		NTP:             true,
+		Syslog:          true,
+		Settings:        map[string]string{"UserVars.SuppressShellWarning": "0", "Missing.Setting": "1"},
+		CertificateDays: 30,
+	}
+	var got Result
+	c.SetSink(func(r Result) error {
+		got = r
+		return nil
+	})
+	err := c.HostCompliance(ctx, hs.Name, "", b, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]string)
+	for _, s := range got.Samples {
+		values[s.Channel] = s.Value
+	}
+	want := map[string]string{
+		"Lockdown Mode":                         "1",
+		"Service TSM-SSH":                       "0",
+		"Service TSM":                           "1",
+		"NTP Configured":                        "1",
+		"NTP Running":                           "1",
+		"Syslog Target":                         "1",
+		"Setting UserVars.SuppressShellWarning": "0",
+		"Setting Missing.Setting":               "0",
+		"Certificate Valid":                     "1",
+		"Compliance (Percent)":                  "66.67",
+	}
+	for ch, v := range want {
+		if values[ch] != v {
+			t.Errorf("%v = %q, want %q", ch, values[ch], v)
+		}
+	}
+	if got.Type != "compliance" || got.Text != "not compliant Service TSM-SSH, Setting Missing.Setting, Setting UserVars.SuppressShellWarning" {
+		t.Errorf("result %v %q", got.Type, got.Text)
+	}
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Profile holds the settings for a single vCenter, keys match the command line flags they replace
+type Profile struct {
+	URL             string   `yaml:"url"`
+	Username        string   `yaml:"username"`
+	PasswordFile    string   `yaml:"password-file"`
+	PasswordCommand string   `yaml:"password-command"`
+	SnapAge         string   `yaml:"snapAge"`
+	Tags            []string `yaml:"tags"`
+	Names           []string `yaml:"names"`
+	Folders         []string `yaml:"folders"`
+	VMMetrics       []string `yaml:"vmMetrics"`
+	MaxWarn         string   `yaml:"maxWarn"`
+	MaxErr          string   `yaml:"maxErr"`
+	MsgWarn         string   `yaml:"msgWarn"`
+	MsgError        string   `yaml:"msgError"`
+	CAFile          string   `yaml:"ca-file"`
+	Thumbprint      string   `yaml:"thumbprint"`
+	Insecure        bool     `yaml:"insecure"`
+	Timeout         string   `yaml:"timeout"`
+	Format          string   `yaml:"format"`
+	ChannelNames    string   `yaml:"channelNames"`
+	Message         string   `yaml:"message"`
+	Baseline        string   `yaml:"baseline"`
+}
+
+// Config holds named vCenter profiles
+type Config struct {
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// DefaultConfigFile is the config file used when none is specified
+func DefaultConfigFile() string {
+	return strings.Join([]string{configDir(), "prtgvmware.yml"}, pathSep)
+}
+
+// DefaultSocketFile is the socket used to reach the collector started with serve
+func DefaultSocketFile() string {
+	return strings.Join([]string{configDir(), "prtgvmware.sock"}, pathSep)
+}
+
+// LoadConfig reads a yaml config file, a missing file returns an empty config
+func LoadConfig(fn string) (cfg *Config, err error) {
+	cfg = &Config{Profiles: make(map[string]Profile)}
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("read config %v", err)
+	}
+
+	err = yaml.UnmarshalStrict(b, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("parse config %v %v", fn, err)
+	}
+	return cfg, nil
+}
+
+// Profile returns a named profile
+func (cfg *Config) Profile(name string) (Profile, error) {
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(cfg.Profiles))
+		for k := range cfg.Profiles {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		return Profile{}, fmt.Errorf("profile %v not found, available profiles %v", name, names)
+	}
+	return p, nil
+}
+
+// Flags returns the profile as flag name to value pairs, empty settings are skipped
+func (p Profile) Flags() map[string]string {
+	f := map[string]string{
+		"url":              p.URL,
+		"username":         p.Username,
+		"password-file":    p.PasswordFile,
+		"password-command": p.PasswordCommand,
+		"snapAge":          p.SnapAge,
+		"tags":             strings.Join(p.Tags, ","),
+		"names":            strings.Join(p.Names, ","),
+		"folders":          strings.Join(p.Folders, ","),
+		"vmMetrics":        strings.Join(p.VMMetrics, ","),
+		"maxWarn":          p.MaxWarn,
+		"maxErr":           p.MaxErr,
+		"msgWarn":          p.MsgWarn,
+		"msgError":         p.MsgError,
+		"ca-file":          p.CAFile,
+		"thumbprint":       p.Thumbprint,
+		"timeout":          p.Timeout,
+		"format":           p.Format,
+		"channelNames":     p.ChannelNames,
+		"message":          p.Message,
+		"baseline":         p.Baseline,
+	}
+	if p.Insecure {
+		f["insecure"] = "true"
+	}
+	for k, v := range f {
+		if v == "" {
+			delete(f, k)
+		}
+	}
+	return f
+}
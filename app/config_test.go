@@ -0,0 +1,110 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testConfig = `
+profiles:
+  vc1:
+    url: https://vc1/sdk
+    username: prtg@vsphere.local
+    snapAge: 48h
+    vmMetrics: [cpu.ready.summation, mem.swapped.average]
+    maxWarn: "2"
+  vc2:
+    url: https://vc2/sdk
+    thumbprint: 2C:11:ED:D7:13:87:7D:B5:74:18:B8:1C:42:C2:56:1F:0D:B9:5B:B9
+  lab:
+    url: https://lab/sdk
+    insecure: true
+`
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prtgvmware")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	fn := filepath.Join(dir, "prtgvmware.yml")
+	err = ioutil.WriteFile(fn, []byte(testConfig), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		profile string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"vc1", fn, "vc1", map[string]string{"url": "https://vc1/sdk", "username": "prtg@vsphere.local", "snapAge": "48h",
+			"vmMetrics": "cpu.ready.summation,mem.swapped.average", "maxWarn": "2"}, false},
+		{"vc2", fn, "vc2", map[string]string{"url": "https://vc2/sdk", "thumbprint": "2C:11:ED:D7:13:87:7D:B5:74:18:B8:1C:42:C2:56:1F:0D:B9:5B:B9"}, false},
+		{"lab", fn, "lab", map[string]string{"url": "https://lab/sdk", "insecure": "true"}, false},
+		{"unknown profile", fn, "vc3", nil, true},
+		{"missing file", filepath.Join(dir, "missing.yml"), "vc1", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadConfig(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := cfg.Profile(tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Profile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			got := p.Flags()
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Flags() got %v want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("Flags() %v got %v want %v", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfigStrict(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prtgvmware")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	fn := filepath.Join(dir, "prtgvmware.yml")
+	err = ioutil.WriteFile(fn, []byte("profiles:\n  vc1:\n    uri: https://vc1/sdk\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(fn); err == nil {
+		t.Fatal("expected error for unknown setting")
+	}
+}
@@ -26,51 +26,70 @@ import (
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/soap"
 	"github.com/vmware/govmomi/vim25/types"
-	"io/ioutil"
-	"log"
+	"io"
 	"net/url"
 	"os"
 	"runtime"
 	"strings"
+	"text/template"
 )
 
 var pathSep = string(os.PathSeparator)
 
 // Client holds the connections we need to query the remote system
 type Client struct {
-	Cached bool
-	c      *vim25.Client
-	r      *rest.Client
-	m      *view.Manager
-	ctx    context.Context
+	Cached   bool
+	c        *vim25.Client
+	r        *rest.Client
+	m        *view.Manager
+	out      io.Writer
+	format   Format
+	limit    ChannelLimit
+	names    *ChannelNames
+	message  *template.Template
+	sink     func(Result) error
+	cache    *clientCache
+	timeouts Timeouts
+	creds    *credentials
+	// pooled is set on clients handed out by a Pool so a new login is shared
+	pooled *pooledClient
 }
 
-// NewClient returns a logged in client
-func NewClient(u *url.URL, user, pw string, cache bool) (c Client, err error) {
+// NewClient returns a logged in client, ctx bounds the login
+func NewClient(ctx context.Context, u *url.URL, user, pw string, cache bool, tlsOpts TLSOptions) (c Client, err error) {
+	defer func() { err = PhaseError(ctx, "login", err) }()
 
 	// load from cache if enabled, will fall through to login code if there are any issues
 	if cache {
-		c, err := clientFromDisk(u.Host, pw, u)
+		c, err := clientFromDisk(ctx, u, user, pw, tlsOpts)
 		if err == nil {
 			c.Cached = true
 			return c, nil
 		}
 	}
 
-	ctx := context.Background()
 	if u.Host == "" {
 		return Client{}, fmt.Errorf("you need to provide a url, I.E https://vcenter/sdk")
 	}
 
 	u.User = url.UserPassword(user, pw)
-	soapClient := soap.NewClient(u, true)
+	soapClient := soap.NewClient(u, tlsOpts.Insecure)
+	err = tlsOpts.apply(soapClient)
+	if err != nil {
+		return c, err
+	}
+	recordTransport(soapClient)
 	c.c, err = vim25.NewClient(ctx, soapClient)
 	if err != nil {
-		return c, fmt.Errorf("unable to connect to %v ", u)
+		return c, fmt.Errorf("unable to connect to %v %v", u.Host, tlsError(err))
+	}
+	// standalone ESXi hosts have no rest api
+	if c.c.IsVC() {
+		c.r = rest.NewClient(c.c)
+		recordTransport(c.r.Client)
 	}
-	c.r = rest.NewClient(c.c)
 
-	err = sessionLogin(c.c, u)
+	err = sessionLogin(ctx, c.c, u)
 	if err != nil {
 		return Client{}, err
 	}
@@ -83,21 +102,22 @@ func NewClient(u *url.URL, user, pw string, cache bool) (c Client, err error) {
 
 		ses, err := c.r.Session(ctx)
 		if err != nil {
-			log.Fatal(err)
+			return c, fmt.Errorf("rest session %v", err)
 		}
 
 		if ses == nil {
-			log.Fatal("expected non-nil session")
+			return c, fmt.Errorf("rest session missing after login")
 		}
 	}
-	err = sessionCheck(c.c)
+	err = sessionCheck(ctx, c.c)
 	if err != nil {
 		return Client{}, err
 	}
 	c.m = view.NewManager(c.c)
-	c.ctx = ctx
+	c.cache = newClientCache()
+	c.creds = &credentials{u: url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}, user: user, pw: pw, cache: cache, tls: tlsOpts}
 	if cache {
-		err := c.save2Disk(u.Host, pw)
+		err := c.save2Disk(u, user, pw)
 		if err != nil {
 			return c, fmt.Errorf("failed to save cached creds to disk")
 		}
@@ -120,36 +140,32 @@ func configDir() string {
 	return dir
 }
 
-func clientFromDisk(fn, password string, u *url.URL) (c Client, err error) {
-	apiCookie := fn + ".api"
-	restCookie := fn + ".rest"
-
-	ccfn := strings.Join([]string{configDir(), apiCookie}, pathSep)
-	rsfn := strings.Join([]string{configDir(), restCookie}, pathSep)
-
-	// check files exist
-	_, err = os.Stat(ccfn)
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
-	_, err = os.Stat(rsfn)
+func clientFromDisk(ctx context.Context, u *url.URL, user, password string, tlsOpts TLSOptions) (c Client, err error) {
+	sf := newSessionFile(u, user)
+	byc, byr, err := sf.read()
+	legacy := false
 	if err != nil {
-		fmt.Println(err)
-		return
+		// fall back to files written before sessions were keyed on user
+		byc, byr, err = readLegacySession(u.Host)
+		if err != nil {
+			return
+		}
+		legacy = true
 	}
 
-	ctx := context.Background()
-
 	// api clinet
 	c = Client{}
-	soapClient := soap.NewClient(u, true)
+	soapClient := soap.NewClient(u, tlsOpts.Insecure)
+	err = tlsOpts.apply(soapClient)
+	if err != nil {
+		return c, err
+	}
+	recordTransport(soapClient)
 	c.c, err = vim25.NewClient(ctx, soapClient)
 	if err != nil {
-		return c, fmt.Errorf("unable to connect to %v ", u)
+		return c, fmt.Errorf("unable to connect to %v %v", u.Host, tlsError(err))
 	}
 
-	byc, err := ioutil.ReadFile(ccfn)
 	bycDecrypted, err := Decrypt(byc, password)
 	if err != nil {
 		return Client{}, fmt.Errorf("could not decrypt creds: %v", err)
@@ -158,88 +174,101 @@ func clientFromDisk(fn, password string, u *url.URL) (c Client, err error) {
 	if err != nil {
 		return Client{}, fmt.Errorf("read api cookie error: %v", err)
 	}
+	// unmarshalling replaces the soap client with one using the settings saved with the session
+	err = tlsOpts.apply(c.c.Client)
+	if err != nil {
+		return Client{}, err
+	}
+	recordTransport(c.c.Client)
 	if c.c.URL().Host != u.Host {
 		c.Cached = false
 		return Client{}, fmt.Errorf("url mismatch, logging back in")
 	}
 
 	if !c.c.Valid() {
-		return
+		return Client{}, fmt.Errorf("cached session invalid")
 	}
-	if !c.c.IsVC() {
-		return
-	}
-	err = sessionCheck(c.c)
+	var mgr mo.SessionManager
+	err = mo.RetrieveProperties(ctx, c.c, c.c.ServiceContent.PropertyCollector, *c.c.ServiceContent.SessionManager, &mgr)
 	if err != nil {
 		return Client{}, fmt.Errorf("failed session check: %v", err)
-
 	}
-
-	// rest client
-	c.r = rest.NewClient(c.c)
-	byr, err := ioutil.ReadFile(rsfn)
-	byrDecrypted, err := Decrypt(byr, password)
-	if err != nil {
-		return Client{}, fmt.Errorf("could not decrypt creds: %v", err)
+	if mgr.CurrentSession == nil {
+		return Client{}, fmt.Errorf("failed session check: not authenticated")
 	}
-	err = c.r.UnmarshalJSON(byrDecrypted)
-	if err != nil {
-		return Client{}, fmt.Errorf("read rest cookie error: %v", err)
+	if legacy && !sameUser(mgr.CurrentSession.UserName, user) {
+		return Client{}, fmt.Errorf("cached session belongs to %v", mgr.CurrentSession.UserName)
+	}
+
+	// rest client, only vcenter has one
+	if c.c.IsVC() {
+		if byr == nil {
+			return Client{}, fmt.Errorf("no rest session cached")
+		}
+		c.r = rest.NewClient(c.c)
+		byrDecrypted, err := Decrypt(byr, password)
+		if err != nil {
+			return Client{}, fmt.Errorf("could not decrypt creds: %v", err)
+		}
+		err = c.r.UnmarshalJSON(byrDecrypted)
+		if err != nil {
+			return Client{}, fmt.Errorf("read rest cookie error: %v", err)
+		}
+		err = tlsOpts.apply(c.r.Client)
+		if err != nil {
+			return Client{}, err
+		}
+		recordTransport(c.r.Client)
 	}
 
-	c.ctx = ctx
 	c.m = view.NewManager(c.c)
+	c.cache = newClientCache()
+	c.creds = &credentials{u: url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}, user: user, pw: password, cache: true, tls: tlsOpts}
+
+	// rewrite files from older releases in the current format and layout
+	if legacy || isLegacy(byc) || (byr != nil && isLegacy(byr)) {
+		err = c.save2Disk(u, user, password)
+		if err == nil && legacy {
+			removeLegacySession(u.Host)
+		}
+		return c, nil
+	}
+	sf.touch()
 	return c, nil
 }
 
-func (c *Client) save2Disk(fn, password string) (err error) {
-	apiCookie := fn + ".api"
-	restCookie := fn + ".rest"
-
-	// make sure we have a directory
-	dir := configDir()
-	err = os.MkdirAll(dir, 0644)
-	if err != nil {
-		return
-	}
+func (c *Client) save2Disk(u *url.URL, user, password string) (err error) {
+	var ccEncrypt, rsEncrypt []byte
 
 	// if we have a valid rest client
 	if c.r != nil {
-		rsfn := strings.Join([]string{configDir(), restCookie}, pathSep)
 		rs, err := c.r.MarshalJSON()
 		if err != nil {
 			return err
 		}
-		rsEncrypt, err := Encrypt(rs, password)
+		rsEncrypt, err = Encrypt(rs, password)
 		if err != nil {
 			return err
-
 		}
-		err = ioutil.WriteFile(rsfn, rsEncrypt, 0644)
-		if err != nil {
-			return err
-		}
-
 	}
 
 	// if we have a valid api client
 	if c.c != nil {
-		ccfn := strings.Join([]string{configDir(), apiCookie}, pathSep)
-
 		cc, err := c.c.MarshalJSON()
 		if err != nil {
 			return err
 		}
-		ccEncrypt, err := Encrypt(cc, password)
-		if err != nil {
-			return err
-		}
-		err = ioutil.WriteFile(ccfn, ccEncrypt, 0644)
+		ccEncrypt, err = Encrypt(cc, password)
 		if err != nil {
 			return err
 		}
 	}
 
+	err = newSessionFile(u, user).write(ccEncrypt, rsEncrypt)
+	if err != nil {
+		return
+	}
+	cleanSessions(sessionMaxAge)
 	return nil
 }
 
@@ -248,23 +277,25 @@ func (c *Client) Logout() error {
 	if c.Cached {
 		return nil
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), logoutTimeout)
+	defer cancel()
 	req := types.Logout{
 		This: *c.c.ServiceContent.SessionManager,
 	}
 	c.c.CloseIdleConnections()
-	_, err := methods.Logout(context.Background(), c.c, &req)
+	_, err := methods.Logout(ctx, c.c, &req)
 	if err != nil {
 		return fmt.Errorf("logout %v", err)
 	}
 	if c.r != nil {
-		_ = c.r.Logout(c.ctx)
+		_ = c.r.Logout(ctx)
 		_ = c.r.CloseIdleConnections
 	}
 
 	return nil
 }
 
-func sessionLogin(c *vim25.Client, ur *url.URL) error {
+func sessionLogin(ctx context.Context, c *vim25.Client, ur *url.URL) error {
 	req := types.Login{
 		This: *c.ServiceContent.SessionManager,
 	}
@@ -274,16 +305,16 @@ func sessionLogin(c *vim25.Client, ur *url.URL) error {
 		req.Password = pw
 	}
 
-	_, err := methods.Login(context.Background(), c, &req)
+	_, err := methods.Login(ctx, c, &req)
 	if err != nil {
 		return fmt.Errorf("login %v", err)
 	}
 	return nil
 }
-func sessionCheck(c *vim25.Client) error {
+func sessionCheck(ctx context.Context, c *vim25.Client) error {
 	var mgr mo.SessionManager
 
-	err := mo.RetrieveProperties(context.Background(), c, c.ServiceContent.PropertyCollector, *c.ServiceContent.SessionManager, &mgr)
+	err := mo.RetrieveProperties(ctx, c, c.ServiceContent.PropertyCollector, *c.ServiceContent.SessionManager, &mgr)
 	if err != nil {
 		return fmt.Errorf("session check %v", err)
 	}
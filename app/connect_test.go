@@ -17,6 +17,7 @@
 package app
 
 import (
+	"context"
 	"testing"
 )
 
@@ -24,19 +25,19 @@ func TestClient_Save2Disk(t *testing.T) {
 
 	tests := []struct {
 		name    string
-		fn      string
+		user    string
 		wantErr bool
 	}{
-		{"", "test", false},
+		{"", user, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			c, err := NewClient(u, user, passwd, true)
+			c, err := NewClient(context.Background(), u, user, passwd, true, TLSOptions{Insecure: true})
 			if err != nil {
 				t.Fatalf(" %v", err)
 			}
 			defer func() { _ = c.Logout() }()
-			if err := c.save2Disk(tt.fn, ".l3tm31n"); (err != nil) != tt.wantErr {
+			if err := c.save2Disk(u, tt.user, ".l3tm31n"); (err != nil) != tt.wantErr {
 				t.Errorf("save2Disk() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -46,14 +47,14 @@ func TestClient_Save2Disk(t *testing.T) {
 func TestNewClientFromDisk(t *testing.T) {
 	tests := []struct {
 		name    string
-		fn      string
+		user    string
 		wantErr bool
 	}{
-		{"", "test", false},
+		{"", user, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := clientFromDisk(tt.fn, ".l3tm31n", u)
+			_, err := clientFromDisk(context.Background(), u, tt.user, ".l3tm31n", TLSOptions{Insecure: true})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("clientFromDisk() error = %v, wantErr %v", err, tt.wantErr)
 				return
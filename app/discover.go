@@ -0,0 +1,124 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/list"
+	"github.com/vmware/govmomi/vim25/types"
+	"path"
+	"strings"
+)
+
+// Selection picks the objects used for discovery,
+// tags need the vcenter tag service, names and folders also work against a standalone ESXi host
+type Selection struct {
+	Tags []string
+	// Names are shell patterns matched against object names, I.E web*
+	Names []string
+	// Folders are inventory paths, everything below them is included, I.E /ha-datacenter/vm
+	Folders []string
+	// Refs are objects listed by a manifest such as a push token file
+	Refs []types.ManagedObjectReference
+}
+
+// Empty is true when nothing would be selected
+func (s Selection) Empty() bool {
+	return len(s.Tags) == 0 && len(s.Names) == 0 && len(s.Folders) == 0 && len(s.Refs) == 0
+}
+
+// args returns the command line flags that repeat this selection
+func (s Selection) args() string {
+	a := make([]string, 0, 3)
+	if len(s.Tags) > 0 {
+		a = append(a, "--tags "+strings.Join(s.Tags, ","))
+	}
+	if len(s.Names) > 0 {
+		a = append(a, "--names "+strings.Join(s.Names, ","))
+	}
+	if len(s.Folders) > 0 {
+		a = append(a, "--folders "+strings.Join(s.Folders, ","))
+	}
+	return strings.Join(a, " ")
+}
+
+func (s Selection) String() string {
+	return s.args()
+}
+
+// discover adds every selected object to tm, keyed on the tag, pattern or folder that selected it
+func (c *Client) discover(ctx context.Context, sel Selection, tm *TagMap) (err error) {
+	if sel.Empty() {
+		return fmt.Errorf("nothing to discover, provide tags, names or folders")
+	}
+	if len(sel.Tags) > 0 && c.r == nil {
+		return fmt.Errorf("tags need the vcenter tag service, use names or folders with standalone hosts")
+	}
+	err = c.list(ctx, sel.Tags, tm)
+	if err != nil {
+		return err
+	}
+
+	if len(sel.Names) > 0 {
+		for _, p := range sel.Names {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("name pattern %v %v", p, err)
+			}
+		}
+		objs, err := c.getmanagedObjectMap(ctx)
+		if err != nil {
+			return fmt.Errorf("list objects %v", err)
+		}
+		for id, o := range objs {
+			for _, p := range sel.Names {
+				if ok, _ := path.Match(p, o.name); ok {
+					tm.add(types.ManagedObjectReference{Type: o.vmwareType, Value: id}, p)
+				}
+			}
+		}
+	}
+
+	for _, ref := range sel.Refs {
+		tm.add(ref, "manifest")
+	}
+
+	for _, f := range sel.Folders {
+		var elems []list.Element
+		err := c.retry(ctx, func() (err error) {
+			elems, err = find.NewFinder(c.c, false).ManagedObjectList(ctx, f)
+			return
+		})
+		if err != nil {
+			return fmt.Errorf("folder %v %v", f, err)
+		}
+		if len(elems) == 0 {
+			return fmt.Errorf("folder %v not found", f)
+		}
+		for _, e := range elems {
+			ids, err := c.getChildIds(ctx, e.Object.Reference())
+			if err != nil {
+				return fmt.Errorf("getChildIds %v", err)
+			}
+			for _, id := range ids {
+				tm.add(id, f)
+			}
+		}
+	}
+	return nil
+}
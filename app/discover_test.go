@@ -0,0 +1,104 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"github.com/vmware/govmomi/simulator"
+	"strings"
+	"testing"
+)
+
+func TestSelectionArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		sel  Selection
+		want string
+	}{
+		{"tags", Selection{Tags: []string{"a", "b"}}, "--tags a,b"},
+		{"names and folders", Selection{Names: []string{"web*"}, Folders: []string{"/ha-datacenter/vm"}}, "--names web* --folders /ha-datacenter/vm"},
+		{"empty", Selection{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sel.args(); got != tt.want {
+				t.Errorf("args() = %q, want %q", got, tt.want)
+			}
+			if tt.sel.Empty() != (tt.want == "") {
+				t.Errorf("Empty() = %v", tt.sel.Empty())
+			}
+		})
+	}
+}
+
+func TestStandaloneESXi(t *testing.T) {
+	s, stop := newSim(t, simulator.ESX(), nil)
+	defer stop()
+
+	c, err := NewClient(context.Background(), s.URL, "user", "pass", false, TLSOptions{Insecure: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.r != nil {
+		t.Error("rest client created for an ESXi host")
+	}
+
+	tests := []struct {
+		name    string
+		sel     Selection
+		want    []string
+		wantErr bool
+	}{
+		{"folder", Selection{Folders: []string{"/ha-datacenter"}}, []string{"localhost.localdomain", "LocalDS_0", "ha-host_VM0", "ha-host_VM1"}, false},
+		{"names", Selection{Names: []string{"*VM1"}}, []string{"ha-host_VM1"}, false},
+		{"tags", Selection{Tags: []string{"prtg"}}, nil, true},
+		{"missing folder", Selection{Folders: []string{"/nope"}}, nil, true},
+	}
+	objs, err := c.getmanagedObjectMap(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := NewTagMap()
+			err := c.discover(context.Background(), tt.sel, tm)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("discover() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			found := make(map[string]bool)
+			for id := range tm.Data {
+				found[objs[id].name] = true
+			}
+			for _, n := range tt.want {
+				if !found[n] {
+					t.Errorf("%v not discovered, got %v", n, found)
+				}
+			}
+		})
+	}
+
+	buf := &bytes.Buffer{}
+	c.SetOutput(buf)
+	err = c.HostSummary(context.Background(), "", "ha-host", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "CPU Capacity MHz") {
+		t.Errorf("unexpected host summary %v", buf.String())
+	}
+}
@@ -17,30 +17,64 @@
 package app
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/md5"
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
+	"golang.org/x/crypto/scrypt"
 	"io"
 )
 
+// cache file layout
+// magic(4) version(1) logN(1) r(1) p(1) salt(16) nonce(12) ciphertext
+const (
+	cacheMagic   = "PVMC"
+	cacheVersion = 1
+	saltSize     = 16
+	headerSize   = len(cacheMagic) + 4 + saltSize
+)
+
+// scrypt cost, logN 15 uses 32MB of memory per key
+var defaultKdf = kdfParams{logN: 15, r: 8, p: 1}
+
+type kdfParams struct {
+	logN, r, p uint8
+}
+
+func (k kdfParams) key(passphrase string, salt []byte) ([]byte, error) {
+	if k.logN < 10 || k.logN > 20 || k.r == 0 || k.p == 0 {
+		return nil, fmt.Errorf("invalid kdf parameters %+v", k)
+	}
+	return scrypt.Key([]byte(passphrase), salt, 1<<k.logN, int(k.r), int(k.p), 32)
+}
+
 func createHash(key string) []byte {
-	// We use an md5 hash here because aes requires a 32 byte key
-	// It doesn't really matter that md5 is not secure because we're not storing the output
+	// legacy key derivation, only used to read cache files written before the versioned format
 	hash := md5.Sum([]byte(key))
 	dst := make([]byte, hex.EncodedLen(len(hash)))
 	hex.Encode(dst, hash[:])
 	return dst
 }
 
+// isLegacy reports whether data was written before the versioned format and needs migrating
+func isLegacy(data []byte) bool {
+	return !bytes.HasPrefix(data, []byte(cacheMagic))
+}
+
 // Encrypt encrypts data using the passphrase.
 func Encrypt(data []byte, passphrase string) ([]byte, error) {
-	block, err := aes.NewCipher(createHash(passphrase))
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return []byte{}, err
+	}
+	key, err := defaultKdf.key(passphrase, salt)
 	if err != nil {
 		return []byte{}, err
 	}
-	gcm, err := cipher.NewGCM(block)
+	gcm, err := newGCM(key)
 	if err != nil {
 		return []byte{}, err
 	}
@@ -48,22 +82,60 @@ func Encrypt(data []byte, passphrase string) ([]byte, error) {
 	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
 		return []byte{}, err
 	}
-	ciphered := gcm.Seal(nonce, nonce, data, nil)
-	return ciphered, nil
+
+	header := make([]byte, 0, headerSize+len(nonce))
+	header = append(header, cacheMagic...)
+	header = append(header, cacheVersion, defaultKdf.logN, defaultKdf.r, defaultKdf.p)
+	header = append(header, salt...)
+	header = append(header, nonce...)
+
+	// header is authenticated as additional data so kdf parameters can't be tampered with
+	return gcm.Seal(header, nonce, data, header[:headerSize]), nil
 }
 
 // Decrypt decrypts data using the passphrase.
 func Decrypt(data []byte, passphrase string) ([]byte, error) {
-	key := createHash(passphrase)
-	block, err := aes.NewCipher(key)
+	if isLegacy(data) {
+		return decryptLegacy(data, passphrase)
+	}
+	if len(data) < headerSize {
+		return []byte{}, fmt.Errorf("cache file truncated")
+	}
+	off := len(cacheMagic)
+	if v := data[off]; v != cacheVersion {
+		return []byte{}, fmt.Errorf("unsupported cache version %v", v)
+	}
+	kdf := kdfParams{logN: data[off+1], r: data[off+2], p: data[off+3]}
+	salt := data[off+4 : headerSize]
+
+	key, err := kdf.key(passphrase, salt)
 	if err != nil {
 		return []byte{}, err
 	}
-	gcm, err := cipher.NewGCM(block)
+	gcm, err := newGCM(key)
+	if err != nil {
+		return []byte{}, err
+	}
+	if len(data) < headerSize+gcm.NonceSize() {
+		return []byte{}, fmt.Errorf("cache file truncated")
+	}
+	nonce, ciphered := data[headerSize:headerSize+gcm.NonceSize()], data[headerSize+gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphered, data[:headerSize])
+	if err != nil {
+		return []byte{}, err
+	}
+	return plaintext, nil
+}
+
+func decryptLegacy(data []byte, passphrase string) ([]byte, error) {
+	gcm, err := newGCM(createHash(passphrase))
 	if err != nil {
 		return []byte{}, err
 	}
 	nonceSize := gcm.NonceSize()
+	if len(data) < nonceSize {
+		return []byte{}, fmt.Errorf("cache file truncated")
+	}
 	nonce, ciphered := data[:nonceSize], data[nonceSize:]
 	plaintext, err := gcm.Open(nil, nonce, ciphered, nil)
 	if err != nil {
@@ -71,3 +143,11 @@ func Decrypt(data []byte, passphrase string) ([]byte, error) {
 	}
 	return plaintext, nil
 }
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
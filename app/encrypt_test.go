@@ -0,0 +1,77 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+func legacyEncrypt(t *testing.T, data []byte, passphrase string) []byte {
+	gcm, err := newGCM(createHash(passphrase))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		t.Fatal(err)
+	}
+	return gcm.Seal(nonce, nonce, data, nil)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	data := []byte(`{"cookie":"vmware_soap_session"}`)
+	current, err := Encrypt(data, ".l3tm31n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown := append([]byte{}, current...)
+	unknown[len(cacheMagic)] = cacheVersion + 1
+	tampered := append([]byte{}, current...)
+	tampered[len(cacheMagic)+1]--
+
+	tests := []struct {
+		name    string
+		data    []byte
+		pw      string
+		legacy  bool
+		wantErr bool
+	}{
+		{"current", current, ".l3tm31n", false, false},
+		{"wrong password", current, "wrong", false, true},
+		{"legacy", legacyEncrypt(t, data, ".l3tm31n"), ".l3tm31n", true, false},
+		{"unknown version", unknown, ".l3tm31n", false, true},
+		{"tampered kdf", tampered, ".l3tm31n", false, true},
+		{"truncated", current[:headerSize-1], ".l3tm31n", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isLegacy(tt.data); got != tt.legacy {
+				t.Errorf("isLegacy() = %v, want %v", got, tt.legacy)
+			}
+			got, err := Decrypt(tt.data, tt.pw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decrypt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(got, data) {
+				t.Errorf("Decrypt() got %s, want %s", got, data)
+			}
+		})
+	}
+}
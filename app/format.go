@@ -0,0 +1,139 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	ps "github.com/PRTG/go-prtg-sensor-api"
+	"io"
+)
+
+// Format selects how sensor results are written
+type Format string
+
+const (
+	// FormatJSON is read by EXE/Script Advanced sensors
+	FormatJSON Format = "prtg-json"
+	// FormatXML is read by EXE/Script Advanced and SSH Script Advanced sensors
+	FormatXML Format = "prtg-xml"
+)
+
+// Formats lists the supported output formats
+var Formats = []Format{FormatJSON, FormatXML}
+
+// ParseFormat checks s is a supported output format, empty selects json
+func ParseFormat(s string) (Format, error) {
+	if s == "" {
+		return FormatJSON, nil
+	}
+	for _, f := range Formats {
+		if Format(s) == f {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported format %v, use one of %v", s, Formats)
+}
+
+// SetFormat sets how sensor results are written, defaults to json
+func (c *Client) SetFormat(f Format) {
+	c.format = f
+}
+
+// sensorResult is the model both encodings are produced from
+type sensorResult struct {
+	channels []ps.SensorChannel
+	text     string
+	err      bool
+}
+
+// xmlChannel mirrors ps.SensorChannel field for field so channels convert directly
+type xmlChannel struct {
+	Channel         string `xml:"channel"`
+	Value           string `xml:"value"`
+	ValueMode       string `xml:"mode,omitempty"`
+	Unit            string `xml:"unit,omitempty"`
+	CustomUnit      string `xml:"customunit,omitempty"`
+	ValueLookup     string `xml:"valuelookup,omitempty"`
+	VolumeSize      string `xml:"volumesize,omitempty"`
+	SpeedSize       string `xml:"speedsize,omitempty"`
+	SpeedTime       string `xml:"speedtime,omitempty"`
+	Float           string `xml:"float,omitempty"`
+	DecimalMode     string `xml:"decimalmode,omitempty"`
+	ShowChart       string `xml:"showchart,omitempty"`
+	ShowTable       string `xml:"showtable,omitempty"`
+	LimitMinWarning string `xml:"limitminwarning,omitempty"`
+	LimitMaxWarning string `xml:"limitmaxwarning,omitempty"`
+	LimitWarningMsg string `xml:"limitwarningmsg,omitempty"`
+	LimitMinError   string `xml:"limitminerror,omitempty"`
+	LimitMaxError   string `xml:"limitmaxerror,omitempty"`
+	LimitErrorMsg   string `xml:"limiterrormsg,omitempty"`
+	LimitMode       string `xml:"limitmode,omitempty"`
+	Warning         string `xml:"warning,omitempty"`
+}
+
+type xmlResult struct {
+	XMLName  xml.Name     `xml:"prtg"`
+	Channels []xmlChannel `xml:"result"`
+	Text     string       `xml:"text,omitempty"`
+	Error    string       `xml:"error"`
+}
+
+// encode writes r to w, indent pretty prints for people reading the output
+func (r sensorResult) encode(w io.Writer, f Format, indent bool) error {
+	errFlag := "0"
+	if r.err {
+		errFlag = "1"
+	}
+
+	switch f {
+	case FormatXML:
+		x := xmlResult{Text: r.text, Error: errFlag}
+		for _, c := range r.channels {
+			x.Channels = append(x.Channels, xmlChannel(c))
+		}
+		var b []byte
+		var err error
+		if indent {
+			b, err = xml.MarshalIndent(x, "", "    ")
+		} else {
+			b, err = xml.Marshal(x)
+		}
+		if err != nil {
+			return fmt.Errorf("marshal xml %v", err)
+		}
+		_, err = fmt.Fprintf(w, "%v%s\n", xml.Header, b)
+		return err
+
+	case FormatJSON, "":
+		s := ps.SensorResponse{SensorResults: ps.SensorResults{SensorChannels: r.channels, Text: r.text, Error: errFlag}}
+		var b []byte
+		var err error
+		if indent {
+			b, err = json.MarshalIndent(s, "", "    ")
+		} else {
+			b, err = json.Marshal(s)
+		}
+		if err != nil {
+			return fmt.Errorf("marshal json %v", err)
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+	return fmt.Errorf("unsupported format %v", f)
+}
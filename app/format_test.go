@@ -0,0 +1,111 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"bytes"
+	"fmt"
+	ps "github.com/PRTG/go-prtg-sensor-api"
+	"strings"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Format
+		wantErr bool
+	}{
+		{"", FormatJSON, false},
+		{"prtg-json", FormatJSON, false},
+		{"prtg-xml", FormatXML, false},
+		{"xml", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseFormat(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseFormat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSensorResultEncode(t *testing.T) {
+	ok := sensorResult{text: "OK <running>", channels: []ps.SensorChannel{
+		{Channel: "Free space (Percent)", Value: "12", Unit: "Percent", DecimalMode: "1", LimitMinWarning: "20", LimitWarningMsg: "Warning Low Space", LimitMode: "1"},
+		{Channel: "Power state", Value: "0", Unit: "Custom", ValueLookup: lookupPowerState},
+	}}
+	failed := sensorResult{text: "login failed", err: true}
+
+	tests := []struct {
+		name   string
+		r      sensorResult
+		f      Format
+		indent bool
+		want   []string
+		absent []string
+	}{
+		{"json channels", ok, FormatJSON, false, []string{
+			`{"prtg":{"result":[{"channel":"Free space (Percent)","value":"12","unit":"Percent","decimalmode":"1","limitminwarning":"20","limitwarningmsg":"Warning Low Space","limitmode":"1"}`,
+			`"valuelookup":"prtgvmware.powerstate"`,
+			`"text":"OK \u003crunning\u003e","error":"0"}}`,
+		}, nil},
+		{"xml channels", ok, FormatXML, false, []string{
+			`<?xml version="1.0" encoding="UTF-8"?>`,
+			`<prtg><result><channel>Free space (Percent)</channel><value>12</value><unit>Percent</unit><decimalmode>1</decimalmode><limitminwarning>20</limitminwarning><limitwarningmsg>Warning Low Space</limitwarningmsg><limitmode>1</limitmode></result>`,
+			`<valuelookup>prtgvmware.powerstate</valuelookup>`,
+			`<text>OK &lt;running&gt;</text><error>0</error></prtg>`,
+		}, []string{"<mode>", "<warning>"}},
+		{"json indented", failed, FormatJSON, true, []string{"{\n    \"prtg\": {\n        \"result\": null,", `"error": "1"`}, nil},
+		{"json error", failed, FormatJSON, false, []string{`{"prtg":{"result":null,"text":"login failed","error":"1"}}`}, nil},
+		{"xml error", failed, FormatXML, false, []string{`<prtg><text>login failed</text><error>1</error></prtg>`}, []string{"<result>"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if err := tt.r.encode(buf, tt.f, tt.indent); err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(buf.String(), s) {
+					t.Errorf("encode() = %v\nmissing %v", buf.String(), s)
+				}
+			}
+			for _, s := range tt.absent {
+				if strings.Contains(buf.String(), s) {
+					t.Errorf("encode() = %v\nunexpected %v", buf.String(), s)
+				}
+			}
+		})
+	}
+}
+
+func TestSensorWarnTo(t *testing.T) {
+	for _, f := range Formats {
+		buf := &bytes.Buffer{}
+		SensorWarnTo(buf, f, fmt.Errorf("busy"), false)
+		for _, s := range []string{"Execution time", "999", "busy"} {
+			if !strings.Contains(buf.String(), s) {
+				t.Errorf("%v SensorWarnTo() = %v, missing %v", f, buf.String(), s)
+			}
+		}
+	}
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"fmt"
+	ps "github.com/PRTG/go-prtg-sensor-api"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// rollupSensor is the numeric sensor ESXi uses for the health of the whole host
+const rollupSensor = "system"
+
+// HardwareHealth reports the sensors and hardware status a host reads from its management controller,
+// every reading gets a channel and every kind of sensor a status channel with the worst state of its sensors
+func (c *Client) HardwareHealth(ctx context.Context, name, moid string, js bool) (err error) {
+	start := time.Now()
+	ictx, cancel := c.inventoryCtx(ctx)
+	defer cancel()
+
+	id := types.ManagedObjectReference{
+		Type:  "HostSystem",
+		Value: moid,
+	}
+	if moid == "" {
+		id, err = c.findOne(ictx, name, id.Type)
+		if err != nil {
+			return PhaseError(ictx, "inventory", err)
+		}
+	}
+	hs := mo.HostSystem{}
+	err = c.retrieveOne(ictx, id, []string{"name", "parent", "runtime"}, &hs)
+	if err != nil {
+		return PhaseError(ictx, "inventory", errCheck(name, id, fmt.Errorf("hs v.properties %v", err)))
+	}
+
+	pr := c.prtgData(types.ManagedObjectReference{Type: "hardware", Value: id.Value}, hs.Name)
+	pr.host, pr.cluster = hs.Name, c.clusterName(ictx, hs.Parent)
+	pr.power = string(hs.Runtime.PowerState)
+	_ = pr.add(connectionState(hs.Runtime.ConnectionState), ps.SensorChannel{Channel: "Connection State", Unit: "Custom", VolumeSize: "Custom", ValueLookup: lookupConnectionState})
+	h := hs.Runtime.HealthSystemRuntime
+	if h == nil {
+		pr.text = fmt.Sprintf("no hardware health reported, host %v", hs.Runtime.ConnectionState)
+		_ = pr.print(time.Since(start), js)
+		return nil
+	}
+
+	groups := make(map[string]int)
+	overall := -1
+	var warnings, alerts int
+	state := func(group, name string, d types.BaseElementDescription) {
+		v := 3
+		if d != nil {
+			v = healthState(d.GetElementDescription().Key)
+		}
+		if w, ok := groups[group]; !ok || healthRank(v) > healthRank(w) {
+			groups[group] = v
+		}
+		switch v {
+		case 1:
+			warnings++
+			pr.failures = append(pr.failures, name)
+		case 2:
+			alerts++
+			pr.failures = append(pr.failures, name)
+		}
+	}
+
+	if h.SystemHealthInfo != nil {
+		for _, s := range h.SystemHealthInfo.NumericSensorInfo {
+			switch s.SensorType {
+			case "Software Components":
+				// installed vib versions, not hardware
+				continue
+			case rollupSensor:
+				if s.HealthState != nil {
+					overall = healthState(s.HealthState.GetElementDescription().Key)
+				}
+				continue
+			}
+			n := sensorName(s.Name)
+			state(sensorGroup(s.SensorType), n, s.HealthState)
+			if s.BaseUnits == "" {
+				continue
+			}
+			ch := sensorUnit(s.BaseUnits)
+			ch.Channel = n
+			_ = pr.addSample(float64(s.CurrentReading)*math.Pow10(int(s.UnitModifier)), ch, strings.ToLower(s.SensorType), n)
+		}
+	}
+	if hw := h.HardwareStatusInfo; hw != nil {
+		for _, e := range hw.MemoryStatusInfo {
+			i := e.GetHostHardwareElementInfo()
+			state("Memory", i.Name, i.Status)
+		}
+		for _, e := range hw.CpuStatusInfo {
+			i := e.GetHostHardwareElementInfo()
+			state("CPU", i.Name, i.Status)
+		}
+		for _, e := range hw.StorageStatusInfo {
+			state("Storage", e.Name, e.Status)
+		}
+	}
+
+	// hosts without the rollup sensor are as healthy as their worst component
+	if overall < 0 {
+		overall = 0
+		for _, v := range groups {
+			if healthRank(v) > healthRank(overall) {
+				overall = v
+			}
+		}
+	}
+	_ = pr.add(overall, ps.SensorChannel{Channel: "Overall Health", Unit: "Custom", VolumeSize: "Custom", ValueLookup: lookupHealth})
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		_ = pr.addSample(groups[k], ps.SensorChannel{Channel: k + " Status", Unit: "Custom", VolumeSize: "Custom", ValueLookup: lookupHealth}, "status", k)
+	}
+	_ = pr.add(warnings, ps.SensorChannel{Channel: "Components Warning", Unit: "Count", LimitMaxWarning: "0", LimitWarningMsg: "hardware warnings", LimitMode: "1"})
+	_ = pr.add(alerts, ps.SensorChannel{Channel: "Components Alert", Unit: "Count", LimitMaxError: "0", LimitErrorMsg: "hardware alerts", LimitMode: "1"})
+	if len(pr.failures) > 0 {
+		pr.text = fmt.Sprintf("%v components failing %v", len(pr.failures), strings.Join(pr.failures, ", "))
+	}
+
+	_ = pr.print(time.Since(start), js)
+	return nil
+}
+
+// sensorName drops the state some hosts append to sensor names, I.E System Board 1 Ambient Temp --- Normal,
+// so channels keep their name when the state changes
+func sensorName(s string) string {
+	if i := strings.Index(s, " --- "); i > 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// sensorGroup is the status channel a sensor type is reported under
+func sensorGroup(t string) string {
+	switch strings.ToLower(t) {
+	case "processors":
+		return "CPU"
+	case "":
+		return "Other"
+	}
+	return strings.ToUpper(t[:1]) + t[1:]
+}
+
+// sensorUnit is the channel unit for a sensor reading, units PRTG doesn't know are shown as reported
+func sensorUnit(u string) ps.SensorChannel {
+	switch strings.ToLower(u) {
+	case "degrees c":
+		return ps.SensorChannel{Unit: "Temperature"}
+	case "percent":
+		return ps.SensorChannel{Unit: "Percent"}
+	case "rpm":
+		return ps.SensorChannel{Unit: "Custom", CustomUnit: "RPM"}
+	case "volts":
+		return ps.SensorChannel{Unit: "Custom", CustomUnit: "V"}
+	case "amps":
+		return ps.SensorChannel{Unit: "Custom", CustomUnit: "A"}
+	case "watts":
+		return ps.SensorChannel{Unit: "Custom", CustomUnit: "W"}
+	}
+	return ps.SensorChannel{Unit: "Custom", CustomUnit: u}
+}
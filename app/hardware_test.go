@@ -0,0 +1,101 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"testing"
+)
+
+func TestHardwareHealth(t *testing.T) {
+	c, stop := newSimClient(t, nil)
+	defer stop()
+	ctx := context.Background()
+
+	sensor := func(name, kind, units, state string, reading int64, modifier int32) types.HostNumericSensorInfo {
+		return types.HostNumericSensorInfo{Name: name, SensorType: kind, BaseUnits: units, CurrentReading: reading, UnitModifier: modifier,
+			HealthState: &types.ElementDescription{Key: state}}
+	}
+	element := func(name, state string) types.HostHardwareElementInfo {
+		return types.HostHardwareElementInfo{Name: name, Status: &types.ElementDescription{Key: state}}
+	}
+	dimm, cpu := element("DIMM A1", "Yellow"), element("Proc 1", "Green")
+	hs := simulator.Map.Get(simulator.Map.Any("HostSystem").Reference()).(*simulator.HostSystem)
+	hs.Runtime.HealthSystemRuntime = &types.HealthSystemRuntime{
+		SystemHealthInfo: &types.HostSystemHealthInfo{NumericSensorInfo: []types.HostNumericSensorInfo{
+			sensor("VMware Rollup Health State", "system", "", "red", 0, 0),
+			sensor("System Board 1 Ambient Temp --- Normal", "temperature", "Degrees C", "green", 2300, -2),
+			sensor("Fan Device 1 Fan 1", "fan", "RPM", "green", 7200, 0),
+			sensor("Power Supply 2 Status", "power", "", "red", 0, 0),
+			sensor("esx-base 7.0", "Software Components", "", "unknown", 0, 0),
+		}},
+		HardwareStatusInfo: &types.HostHardwareStatusInfo{
+			MemoryStatusInfo:  []types.BaseHostHardwareElementInfo{&dimm},
+			CpuStatusInfo:     []types.BaseHostHardwareElementInfo{&cpu},
+			StorageStatusInfo: []types.HostStorageElementInfo{{HostHardwareElementInfo: element("Controller 0", "Unknown")}},
+		},
+	}
+
+	var got Result
+	c.SetSink(func(r Result) error {
+		got = r
+		return nil
+	})
+	err := c.HardwareHealth(ctx, hs.Name, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]string)
+	for _, s := range got.Samples {
+		values[s.Channel] = s.Value
+	}
+	want := map[string]string{
+		"Overall Health":              "2",
+		"System Board 1 Ambient Temp": "23.00",
+		"Fan Device 1 Fan 1":          "7200.00",
+		"Power Status":                "2",
+		"Temperature Status":          "0",
+		"Memory Status":               "1",
+		"CPU Status":                  "0",
+		"Storage Status":              "3",
+		"Components Warning":          "1",
+		"Components Alert":            "1",
+	}
+	for ch, v := range want {
+		if values[ch] != v {
+			t.Errorf("%v = %q, want %q", ch, values[ch], v)
+		}
+	}
+	if _, ok := values["Software Components Status"]; ok {
+		t.Errorf("software components reported %v", values)
+	}
+	if got.Type != "hardware" || got.Text != "failing Power Supply 2 Status, DIMM A1" {
+		t.Errorf("result %v %q", got.Type, got.Text)
+	}
+
+	// disconnected hosts report no health
+	hs.Runtime.HealthSystemRuntime = nil
+	err = c.HardwareHealth(ctx, hs.Name, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Samples) != 1 || got.Text == "" {
+		t.Errorf("no health %+v", got)
+	}
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LineFormat selects the time series protocol results are written in
+type LineFormat string
+
+const (
+	// LineInflux is the InfluxDB line protocol
+	LineInflux LineFormat = "influx"
+	// LineGraphite is the Graphite plaintext protocol with tagged series
+	LineGraphite LineFormat = "graphite"
+)
+
+// LineFormats lists the supported time series protocols
+var LineFormats = []LineFormat{LineInflux, LineGraphite}
+
+// ParseLineFormat checks s is a supported time series protocol
+func ParseLineFormat(s string) (LineFormat, error) {
+	for _, f := range LineFormats {
+		if LineFormat(s) == f {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported line format %v, use one of %v", s, LineFormats)
+}
+
+// LineEncoder turns results into InfluxDB or Graphite lines
+type LineEncoder struct {
+	Format LineFormat
+	// Prefix starts every measurement or metric path, defaults to vmware
+	Prefix  string
+	VCenter string
+}
+
+// lineField is a value of a result with what tells it apart from the object's other values
+type lineField struct {
+	name, instance string
+	value          float64
+}
+
+// fields flattens r into named values, every result carries collect_error and successful ones collect_duration_seconds
+func (r Result) fields() []lineField {
+	if r.Err != "" {
+		return []lineField{{name: "collect_error", value: 1}}
+	}
+	f := make([]lineField, 0, len(r.Samples)+2)
+	f = append(f, lineField{name: "collect_error"}, lineField{name: "collect_duration_seconds", value: r.Duration.Seconds()})
+	for _, s := range r.Samples {
+		v, ok := s.Float()
+		if !ok {
+			continue
+		}
+		f = append(f, lineField{name: metricName(s.Key()), instance: s.Instance, value: v})
+	}
+	return f
+}
+
+// Encode appends the lines for r to b
+func (e LineEncoder) Encode(b *bytes.Buffer, r Result) error {
+	prefix := e.Prefix
+	if prefix == "" {
+		prefix = "vmware"
+	}
+	kind := shortType(r.Type)
+	if kind == "" {
+		kind = "object"
+	}
+	tags := [][2]string{{"vcenter", e.VCenter}, {"type", r.Type}, {"moid", r.Moid}, {"name", r.Name}, {"tags", strings.Join(r.Tags, ",")}}
+
+	switch e.Format {
+	case LineInflux:
+		e.influx(b, prefix+"_"+kind, tags, r)
+	case LineGraphite:
+		e.graphite(b, prefix+"."+kind, tags, r)
+	default:
+		return fmt.Errorf("unsupported line format %v", e.Format)
+	}
+	return nil
+}
+
+// influx writes one line per instance holding all of its fields, empty tags are left out as influx rejects them
+func (e LineEncoder) influx(b *bytes.Buffer, measurement string, tags [][2]string, r Result) {
+	byInstance := make(map[string][]lineField)
+	for _, f := range r.fields() {
+		byInstance[f.instance] = append(byInstance[f.instance], f)
+	}
+	instances := make([]string, 0, len(byInstance))
+	for i := range byInstance {
+		instances = append(instances, i)
+	}
+	sort.Strings(instances)
+
+	for _, i := range instances {
+		b.WriteString(influxEscape(measurement, false))
+		for _, t := range append(tags[:len(tags):len(tags)], [2]string{"instance", i}) {
+			if t[1] == "" {
+				continue
+			}
+			b.WriteString("," + influxEscape(t[0], true) + "=" + influxEscape(t[1], true))
+		}
+		seen := make(map[string]bool)
+		sep := " "
+		for _, f := range byInstance[i] {
+			// channels sharing a family on one instance keep the first value
+			if seen[f.name] {
+				continue
+			}
+			seen[f.name] = true
+			b.WriteString(sep + influxEscape(f.name, true) + "=" + strconv.FormatFloat(f.value, 'g', -1, 64))
+			sep = ","
+		}
+		fmt.Fprintf(b, " %d\n", r.Time.UnixNano())
+	}
+}
+
+// influxEscape escapes a measurement, or a tag or field key or value when tag is set
+func influxEscape(s string, tag bool) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, ",", `\,`, -1)
+	s = strings.Replace(s, " ", `\ `, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	if tag {
+		s = strings.Replace(s, "=", `\=`, -1)
+	}
+	return s
+}
+
+// graphite writes one tagged series per field, I.E vmware.vm.cpu_usage_average;moid=vm-1 12.5 1560000000
+func (e LineEncoder) graphite(b *bytes.Buffer, path string, tags [][2]string, r Result) {
+	ts := r.Time.Unix()
+	for _, f := range r.fields() {
+		b.WriteString(path + "." + f.name)
+		for _, t := range append(tags[:len(tags):len(tags)], [2]string{"instance", f.instance}) {
+			if v := graphiteTag(t[1]); v != "" {
+				b.WriteString(";" + t[0] + "=" + v)
+			}
+		}
+		fmt.Fprintf(b, " %v %d\n", strconv.FormatFloat(f.value, 'g', -1, 64), ts)
+	}
+}
+
+// graphiteTag replaces characters graphite doesn't allow in tag values, or that end the series name
+func graphiteTag(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ';', '~', '!', '^', ' ', '\t', '\n', '\r':
+			return '_'
+		}
+		return r
+	}, s)
+}
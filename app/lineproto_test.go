@@ -0,0 +1,83 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"bytes"
+	ps "github.com/PRTG/go-prtg-sensor-api"
+	"testing"
+	"time"
+)
+
+func TestParseLineFormat(t *testing.T) {
+	for _, s := range []string{"influx", "graphite"} {
+		if f, err := ParseLineFormat(s); err != nil || string(f) != s {
+			t.Errorf("ParseLineFormat(%v) = %v %v", s, f, err)
+		}
+	}
+	if _, err := ParseLineFormat("prtg-json"); err == nil {
+		t.Error("expected unsupported format error")
+	}
+}
+
+func TestLineEncoder(t *testing.T) {
+	ts := time.Unix(1560000000, 5)
+	vm := Result{Type: "VirtualMachine", Moid: "vm-1", Name: "web 1,a=b", Tags: []string{"prtg", "web*"}, Duration: 1500 * time.Millisecond, Time: ts, Samples: []Sample{
+		{SensorChannel: ps.SensorChannel{Channel: "cpu.usage.average", Value: "12.50"}, Family: "cpu.usage.average"},
+		{SensorChannel: ps.SensorChannel{Channel: "free Bytes /", Value: "100"}, Family: "guest disk free bytes", Instance: "/"},
+		{SensorChannel: ps.SensorChannel{Channel: "free Bytes /boot", Value: "50"}, Family: "guest disk free bytes", Instance: "/boot"},
+		{SensorChannel: ps.SensorChannel{Channel: "text", Value: "n/a"}},
+	}}
+	failed := Result{Type: "HostSystem", Moid: "host-1", Name: "esx1", Err: "timed out", Time: ts}
+
+	tests := []struct {
+		name string
+		enc  LineEncoder
+		r    Result
+		want string
+	}{
+		{"influx", LineEncoder{Format: LineInflux, VCenter: "vc1"}, vm,
+			`vmware_vm,vcenter=vc1,type=VirtualMachine,moid=vm-1,name=web\ 1\,a\=b,tags=prtg\,web* collect_error=0,collect_duration_seconds=1.5,cpu_usage_average=12.5 1560000000000000005` + "\n" +
+				`vmware_vm,vcenter=vc1,type=VirtualMachine,moid=vm-1,name=web\ 1\,a\=b,tags=prtg\,web*,instance=/ guest_disk_free_bytes=100 1560000000000000005` + "\n" +
+				`vmware_vm,vcenter=vc1,type=VirtualMachine,moid=vm-1,name=web\ 1\,a\=b,tags=prtg\,web*,instance=/boot guest_disk_free_bytes=50 1560000000000000005` + "\n"},
+		{"influx error", LineEncoder{Format: LineInflux, Prefix: "vc"}, failed,
+			"vc_host,type=HostSystem,moid=host-1,name=esx1 collect_error=1 1560000000000000005\n"},
+		{"graphite", LineEncoder{Format: LineGraphite, VCenter: "vc1"}, vm,
+			"vmware.vm.collect_error;vcenter=vc1;type=VirtualMachine;moid=vm-1;name=web_1,a=b;tags=prtg,web* 0 1560000000\n" +
+				"vmware.vm.collect_duration_seconds;vcenter=vc1;type=VirtualMachine;moid=vm-1;name=web_1,a=b;tags=prtg,web* 1.5 1560000000\n" +
+				"vmware.vm.cpu_usage_average;vcenter=vc1;type=VirtualMachine;moid=vm-1;name=web_1,a=b;tags=prtg,web* 12.5 1560000000\n" +
+				"vmware.vm.guest_disk_free_bytes;vcenter=vc1;type=VirtualMachine;moid=vm-1;name=web_1,a=b;tags=prtg,web*;instance=/ 100 1560000000\n" +
+				"vmware.vm.guest_disk_free_bytes;vcenter=vc1;type=VirtualMachine;moid=vm-1;name=web_1,a=b;tags=prtg,web*;instance=/boot 50 1560000000\n"},
+		{"graphite missing object", LineEncoder{Format: LineGraphite}, Result{Moid: "vm-9", Err: "object vm-9 not found", Time: ts},
+			"vmware.object.collect_error;moid=vm-9 1 1560000000\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			if err := tt.enc.Encode(b, tt.r); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("Encode() got\n%v\nwant\n%v", b, tt.want)
+			}
+		})
+	}
+
+	if err := (LineEncoder{Format: "csv"}).Encode(&bytes.Buffer{}, vm); err == nil {
+		t.Error("expected unsupported format error")
+	}
+}
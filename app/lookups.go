@@ -0,0 +1,259 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/vmware/govmomi/vim25/types"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// value lookups shipped by the lookups command, channels refer to them by id
+const (
+	lookupStatus          = "prtgvmware.status"
+	lookupPowerState      = "prtgvmware.powerstate"
+	lookupToolsStatus     = "prtgvmware.toolsstatus"
+	lookupToolsRunning    = "prtgvmware.toolsrunning"
+	lookupConnectionState = "prtgvmware.connectionstate"
+	lookupMaintenanceMode = "prtgvmware.maintenancemode"
+	lookupEnabled         = "prtgvmware.enabled"
+	lookupDrsBehavior     = "prtgvmware.drsbehavior"
+	lookupVMMonitoring    = "prtgvmware.vmmonitoring"
+	lookupHealth          = "prtgvmware.health"
+	lookupCompliance      = "prtgvmware.compliance"
+)
+
+// LookupValue is one value of a lookup and the sensor state it puts the channel in
+type LookupValue struct {
+	Value int    `xml:"value,attr"`
+	State string `xml:"state,attr"`
+	Text  string `xml:",chardata"`
+}
+
+// Lookup is a PRTG value lookup, saved as an .ovl file in the PRTG lookups\custom folder
+type Lookup struct {
+	XMLName        xml.Name      `xml:"ValueLookup"`
+	ID             string        `xml:"id,attr"`
+	DesiredValue   int           `xml:"desiredValue,attr"`
+	UndefinedState string        `xml:"undefinedState,attr"`
+	XSI            string        `xml:"xmlns:xsi,attr"`
+	Schema         string        `xml:"xsi:noNamespaceSchemaLocation,attr"`
+	Values         []LookupValue `xml:"Lookups>SingleInt"`
+}
+
+func newLookup(id string, desired int, values ...LookupValue) Lookup {
+	return Lookup{
+		ID:             id,
+		DesiredValue:   desired,
+		UndefinedState: "Warning",
+		XSI:            "http://www.w3.org/2001/XMLSchema-instance",
+		Schema:         "PaeValueLookup.xsd",
+		Values:         values,
+	}
+}
+
+// Lookups are the value lookups used by the summaries
+var Lookups = []Lookup{
+	newLookup(lookupStatus, 0,
+		LookupValue{0, "Ok", "Green"},
+		LookupValue{1, "Warning", "Yellow"},
+		LookupValue{2, "Error", "Red"},
+		LookupValue{3, "Unknown", "Gray"},
+	),
+	newLookup(lookupPowerState, 0,
+		LookupValue{0, "Ok", "Powered on"},
+		LookupValue{1, "Warning", "Powered off"},
+		LookupValue{2, "Warning", "Standby"},
+		LookupValue{3, "Error", "Unknown"},
+	),
+	newLookup(lookupToolsStatus, 0,
+		LookupValue{0, "Ok", "Tools ok"},
+		LookupValue{1, "Warning", "Tools old"},
+		LookupValue{2, "Warning", "Tools not running"},
+		LookupValue{3, "Warning", "Tools not installed"},
+	),
+	newLookup(lookupToolsRunning, 1,
+		LookupValue{0, "Ok", "Not running"},
+		LookupValue{1, "Ok", "Running"},
+	),
+	newLookup(lookupConnectionState, 0,
+		LookupValue{0, "Ok", "Connected"},
+		LookupValue{1, "Error", "Disconnected"},
+		LookupValue{2, "Error", "Not responding"},
+	),
+	newLookup(lookupMaintenanceMode, 0,
+		LookupValue{0, "Ok", "Normal"},
+		LookupValue{1, "Warning", "In maintenance mode"},
+	),
+	newLookup(lookupEnabled, 1,
+		LookupValue{0, "Warning", "Disabled"},
+		LookupValue{1, "Ok", "Enabled"},
+	),
+	newLookup(lookupDrsBehavior, 0,
+		LookupValue{0, "Ok", "Fully automated"},
+		LookupValue{1, "Ok", "Partially automated"},
+		LookupValue{2, "Ok", "Manual"},
+	),
+	newLookup(lookupVMMonitoring, 0,
+		LookupValue{0, "Ok", "Disabled"},
+		LookupValue{1, "Ok", "VM monitoring"},
+		LookupValue{2, "Ok", "VM and application monitoring"},
+	),
+	newLookup(lookupHealth, 0,
+		LookupValue{0, "Ok", "Normal"},
+		LookupValue{1, "Warning", "Warning"},
+		LookupValue{2, "Error", "Alert"},
+		LookupValue{3, "Unknown", "Unknown"},
+	),
+	newLookup(lookupCompliance, 1,
+		LookupValue{0, "Warning", "Not compliant"},
+		LookupValue{1, "Ok", "Compliant"},
+	),
+}
+
+// WriteLookups saves every lookup to dir as <id>.ovl and returns the files written
+func WriteLookups(dir string) ([]string, error) {
+	files := make([]string, 0, len(Lookups))
+	for _, l := range Lookups {
+		b, err := xml.MarshalIndent(l, "", "  ")
+		if err != nil {
+			return files, fmt.Errorf("marshal lookup %v %v", l.ID, err)
+		}
+		fn := filepath.Join(dir, l.ID+".ovl")
+		err = ioutil.WriteFile(fn, append([]byte(xml.Header), append(b, '\n')...), 0644)
+		if err != nil {
+			return files, fmt.Errorf("write lookup %v", err)
+		}
+		files = append(files, fn)
+	}
+	return files, nil
+}
+
+// managedEntityStatus maps a vSphere status colour to the prtgvmware.status lookup
+func managedEntityStatus(s types.ManagedEntityStatus) int {
+	switch s {
+	case types.ManagedEntityStatusGreen:
+		return 0
+	case types.ManagedEntityStatusYellow:
+		return 1
+	case types.ManagedEntityStatusRed:
+		return 2
+	}
+	return 3
+}
+
+// powerState maps host and vm power states to the prtgvmware.powerstate lookup
+func powerState(s string) int {
+	switch s {
+	case "poweredOn":
+		return 0
+	case "poweredOff":
+		return 1
+	case "standby", "suspended":
+		return 2
+	}
+	return 3
+}
+
+// toolsStatus maps the vm tools status to the prtgvmware.toolsstatus lookup
+func toolsStatus(s types.VirtualMachineToolsStatus) int {
+	switch s {
+	case types.VirtualMachineToolsStatusToolsOk:
+		return 0
+	case types.VirtualMachineToolsStatusToolsOld:
+		return 1
+	case types.VirtualMachineToolsStatusToolsNotRunning:
+		return 2
+	}
+	return 3
+}
+
+// connectionState maps the host connection state to the prtgvmware.connectionstate lookup
+func connectionState(s types.HostSystemConnectionState) int {
+	switch s {
+	case types.HostSystemConnectionStateConnected:
+		return 0
+	case types.HostSystemConnectionStateDisconnected:
+		return 1
+	}
+	return 2
+}
+
+// drsBehavior maps the cluster DRS automation level to the prtgvmware.drsbehavior lookup, unset is fully automated
+func drsBehavior(b types.DrsBehavior) int {
+	switch b {
+	case types.DrsBehaviorFullyAutomated, "":
+		return 0
+	case types.DrsBehaviorPartiallyAutomated:
+		return 1
+	case types.DrsBehaviorManual:
+		return 2
+	}
+	return 3
+}
+
+// storageDrsBehavior maps the storage DRS automation level to the prtgvmware.drsbehavior lookup
+func storageDrsBehavior(s string) int {
+	switch types.StorageDrsPodConfigInfoBehavior(s) {
+	case types.StorageDrsPodConfigInfoBehaviorAutomated:
+		return 0
+	case types.StorageDrsPodConfigInfoBehaviorManual:
+		return 2
+	}
+	return 3
+}
+
+// vmMonitoring maps the HA vm monitoring setting to the prtgvmware.vmmonitoring lookup
+func vmMonitoring(s string) int {
+	switch types.ClusterDasConfigInfoVmMonitoringState(s) {
+	case types.ClusterDasConfigInfoVmMonitoringStateVmMonitoringOnly:
+		return 1
+	case types.ClusterDasConfigInfoVmMonitoringStateVmAndAppMonitoring:
+		return 2
+	}
+	return 0
+}
+
+// healthState maps a host sensor or hardware element state to the prtgvmware.health lookup,
+// sensors report green and hardware elements Green
+func healthState(key string) int {
+	switch strings.ToLower(key) {
+	case "green":
+		return 0
+	case "yellow":
+		return 1
+	case "red":
+		return 2
+	}
+	return 3
+}
+
+// healthRank orders prtgvmware.health values from healthy to failed, unknown is worse than normal
+func healthRank(v int) int {
+	switch v {
+	case 0:
+		return 0
+	case 3:
+		return 1
+	case 1:
+		return 2
+	}
+	return 3
+}
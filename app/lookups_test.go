@@ -0,0 +1,107 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"encoding/xml"
+	"github.com/vmware/govmomi/vim25/types"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWriteLookups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prtgvmware")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	files, err := WriteLookups(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[string]bool)
+	for _, fn := range files {
+		b, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(b), xml.Header) || !strings.Contains(string(b), `xsi:noNamespaceSchemaLocation="PaeValueLookup.xsd"`) {
+			t.Errorf("%v is not a PRTG lookup\n%s", fn, b)
+		}
+		l := Lookup{}
+		if err := xml.Unmarshal(b, &l); err != nil {
+			t.Fatalf("%v %v", fn, err)
+		}
+		if !strings.HasSuffix(fn, l.ID+".ovl") || len(l.Values) < 2 {
+			t.Errorf("%v holds %+v", fn, l)
+		}
+		ids[l.ID] = true
+	}
+	for _, id := range []string{lookupStatus, lookupPowerState, lookupToolsStatus, lookupToolsRunning, lookupConnectionState, lookupMaintenanceMode, lookupEnabled, lookupDrsBehavior, lookupVMMonitoring, lookupHealth, lookupCompliance} {
+		if !ids[id] {
+			t.Errorf("no lookup file for %v", id)
+		}
+	}
+}
+
+func TestLookupValues(t *testing.T) {
+	tests := []struct {
+		name      string
+		got, want int
+	}{
+		{"green", managedEntityStatus(types.ManagedEntityStatusGreen), 0},
+		{"red", managedEntityStatus(types.ManagedEntityStatusRed), 2},
+		{"gray", managedEntityStatus(types.ManagedEntityStatusGray), 3},
+		{"powered on", powerState("poweredOn"), 0},
+		{"standby", powerState("standby"), 2},
+		{"power unknown", powerState("unknown"), 3},
+		{"tools old", toolsStatus(types.VirtualMachineToolsStatusToolsOld), 1},
+		{"tools not installed", toolsStatus(types.VirtualMachineToolsStatusToolsNotInstalled), 3},
+		{"not responding", connectionState(types.HostSystemConnectionStateNotResponding), 2},
+		{"drs unset", drsBehavior(""), 0},
+		{"drs manual", drsBehavior(types.DrsBehaviorManual), 2},
+		{"vm and app monitoring", vmMonitoring(string(types.ClusterDasConfigInfoVmMonitoringStateVmAndAppMonitoring)), 2},
+		{"vm monitoring unset", vmMonitoring(""), 0},
+		{"sensor green", healthState("green"), 0},
+		{"element Red", healthState("Red"), 2},
+		{"health unknown", healthState("Unknown"), 3},
+		{"unknown worse than normal", healthRank(3), 1},
+		{"alert worst", healthRank(2), 3},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%v = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// every value a channel can report has a text in its lookup
+	for _, l := range Lookups {
+		seen := make(map[int]bool)
+		for _, v := range l.Values {
+			if seen[v.Value] || v.Text == "" {
+				t.Errorf("%v value %v repeated or without text", l.ID, v.Value)
+			}
+			seen[v.Value] = true
+		}
+		if !seen[l.DesiredValue] {
+			t.Errorf("%v desired value %v not defined", l.ID, l.DesiredValue)
+		}
+	}
+}
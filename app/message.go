@@ -0,0 +1,221 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	ps "github.com/PRTG/go-prtg-sensor-api"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultMessages are the sensor messages used without --message, keyed by object type
+var DefaultMessages = map[string]string{
+	"VirtualMachine":                 `{{if eq .PowerState "poweredOn"}}running{{else}}{{.PowerState}}{{end}}{{with .Host}} on host {{.}}{{end}}{{with .Cluster}} in {{.}}{{end}}{{with .Problems}}, {{join . ", "}}{{end}}`,
+	"HostSystem":                     `{{.PowerState}}{{with .Cluster}} in {{.}}{{end}}{{if .Uptime}}, up {{human .Uptime}}{{end}}{{with .Failures}}, path failure {{join . ", "}}{{end}}{{with .Problems}}, {{join . ", "}}{{end}}`,
+	"Datastore":                      `{{with .Problems}}{{join . ", "}}{{end}}`,
+	"ClusterComputeResource":         `{{with .Failures}}disconnected {{join . ", "}}{{end}}{{with .Problems}}{{if $.Failures}}, {{end}}{{join . ", "}}{{end}}`,
+	"StoragePod":                     `{{with .Failures}}inaccessible {{join . ", "}}{{end}}{{with .Problems}}{{if $.Failures}}, {{end}}{{join . ", "}}{{end}}`,
+	"ResourcePool":                   `{{with .Problems}}{{join . ", "}}{{end}}`,
+	"DistributedVirtualPortgroup":    `{{.Text}}{{with .Problems}}{{if $.Text}}, {{end}}{{join . ", "}}{{end}}`,
+	"VmwareDistributedVirtualSwitch": `{{with .Problems}}{{len .}} not green, worst {{index . 0}}{{end}}`,
+	"compliance":                     `{{with .Failures}}not compliant {{join . ", "}}{{end}}`,
+	"hardware":                       `{{with .Failures}}failing {{join . ", "}}{{else}}{{.Text}}{{end}}`,
+	"snapshots":                      `{{with .Problems}}{{len .}} vms with old snapshots, {{join . ", "}}{{end}}`,
+}
+
+// defaultMessage is used for types without a default of their own
+const defaultMessage = `{{.Text}}`
+
+// MessageData is what --message templates can use, I.E {{.Name}} on {{.Host}} {{with .Worst}}{{.Channel}} {{.State}}{{end}}
+type MessageData struct {
+	Type, Moid, Name string
+	Host, Cluster    string
+	PowerState       string
+	Uptime           time.Duration
+	// Text is the message the summary would have written without templates
+	Text string
+	// Failures are problems found that have no channel of their own, I.E failed storage paths
+	Failures []string
+	// Channels holds every channel value by channel name
+	Channels map[string]string
+	// Problems lists the channels in a warning or error state, worst first
+	Problems []string
+	// Worst is the channel in the worst state, nil when everything is ok
+	Worst *ChannelState
+}
+
+// ChannelState is a channel value with the state its limits or lookup put it in
+type ChannelState struct {
+	Channel, Value string
+	// State is Ok, Warning or Error
+	State string
+	rank  int
+}
+
+func (s ChannelState) String() string {
+	return s.Channel + ": " + s.Value
+}
+
+var messageFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"human": humanDuration,
+}
+
+// ParseMessage checks a --message template
+func ParseMessage(s string) (*template.Template, error) {
+	t, err := template.New("message").Funcs(messageFuncs).Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("message template %v", err)
+	}
+	return t, nil
+}
+
+// SetMessage sets the template used for sensor messages, nil uses DefaultMessages
+func (c *Client) SetMessage(t *template.Template) {
+	c.message = t
+}
+
+// humanDuration rounds d to days and hours, or hours and minutes for shorter durations
+func humanDuration(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd %dh", d/(24*time.Hour), d%(24*time.Hour)/time.Hour)
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh %dm", d/time.Hour, d%time.Hour/time.Minute)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
+
+// channelState evaluates the limits and lookup of c the way PRTG would
+func channelState(c ps.SensorChannel) ChannelState {
+	s := ChannelState{Channel: c.Channel, Value: c.Value, State: "Ok"}
+	set := func(state string, rank int) {
+		if rank > s.rank {
+			s.State, s.rank = state, rank
+		}
+	}
+	if c.Warning == "1" {
+		set("Warning", 1)
+	}
+	v, err := strconv.ParseFloat(c.Value, 64)
+	if err != nil {
+		return s
+	}
+	over := func(limit string, max bool) bool {
+		l, err := strconv.ParseFloat(limit, 64)
+		if err != nil {
+			return false
+		}
+		if max {
+			return v > l
+		}
+		return v < l
+	}
+	if over(c.LimitMaxWarning, true) || over(c.LimitMinWarning, false) {
+		set("Warning", 1)
+	}
+	if over(c.LimitMaxError, true) || over(c.LimitMinError, false) {
+		set("Error", 2)
+	}
+	for _, l := range Lookups {
+		if l.ID != c.ValueLookup {
+			continue
+		}
+		state := l.UndefinedState
+		for _, lv := range l.Values {
+			if float64(lv.Value) == v {
+				state = lv.State
+				s.Value = lv.Text
+			}
+		}
+		switch state {
+		case "Error":
+			set("Error", 2)
+		case "Warning", "Unknown":
+			set("Warning", 1)
+		}
+	}
+	return s
+}
+
+// messageData collects what templates can use
+func (p *prtgData) messageData() MessageData {
+	d := MessageData{
+		Type: p.kind, Moid: p.moid, Name: p.name,
+		Host: p.host, Cluster: p.cluster, PowerState: p.power, Uptime: p.uptime,
+		Text: p.text, Failures: p.failures,
+		Channels: make(map[string]string, len(p.items)),
+	}
+	states := make([]ChannelState, 0)
+	for _, c := range p.items {
+		d.Channels[c.Channel] = c.Value
+		if s := channelState(c); s.rank > 0 {
+			states = append(states, s)
+		}
+	}
+	sort.SliceStable(states, func(i, j int) bool {
+		if states[i].rank != states[j].rank {
+			return states[i].rank > states[j].rank
+		}
+		return states[i].Channel < states[j].Channel
+	})
+	for _, s := range states {
+		d.Problems = append(d.Problems, s.String())
+	}
+	if len(states) > 0 {
+		d.Worst = &states[0]
+	}
+	return d
+}
+
+// messageText renders the sensor message, a failing template falls back to the summary text
+func (p *prtgData) messageText() string {
+	t := p.message
+	if t == nil {
+		s, ok := DefaultMessages[p.kind]
+		if !ok {
+			s = defaultMessage
+		}
+		t = template.Must(template.New("default").Funcs(messageFuncs).Parse(s))
+	}
+	b := &bytes.Buffer{}
+	err := t.Execute(b, p.messageData())
+	if err != nil {
+		return strings.TrimSpace(fmt.Sprintf("%v message template %v", p.text, err))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// clusterName returns the name of the cluster owning a host, hosts outside a cluster have none
+func (c *Client) clusterName(ctx context.Context, parent *types.ManagedObjectReference) string {
+	if parent == nil || parent.Type != "ClusterComputeResource" {
+		return ""
+	}
+	cl := mo.ClusterComputeResource{}
+	if c.retrieveOne(ctx, *parent, []string{"name"}, &cl) != nil {
+		return ""
+	}
+	return cl.Name
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	ps "github.com/PRTG/go-prtg-sensor-api"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChannelState(t *testing.T) {
+	tests := []struct {
+		name  string
+		ch    ps.SensorChannel
+		state string
+		value string
+	}{
+		{"no limits", ps.SensorChannel{Channel: "a", Value: "5"}, "Ok", "5"},
+		{"min warning", ps.SensorChannel{Channel: "a", Value: "15", LimitMinWarning: "20", LimitMinError: "10"}, "Warning", "15"},
+		{"min error", ps.SensorChannel{Channel: "a", Value: "5", LimitMinWarning: "20", LimitMinError: "10"}, "Error", "5"},
+		{"on the limit", ps.SensorChannel{Channel: "a", Value: "1", LimitMaxWarning: "1"}, "Ok", "1"},
+		{"warning flag", ps.SensorChannel{Channel: "a", Value: "0", Warning: "1"}, "Warning", "0"},
+		{"lookup ok", ps.SensorChannel{Channel: "a", Value: "0", ValueLookup: lookupConnectionState}, "Ok", "Connected"},
+		{"lookup error", ps.SensorChannel{Channel: "a", Value: "1", ValueLookup: lookupConnectionState}, "Error", "Disconnected"},
+		{"lookup undefined", ps.SensorChannel{Channel: "a", Value: "9", ValueLookup: lookupConnectionState}, "Warning", "9"},
+		{"text value", ps.SensorChannel{Channel: "a", Value: "n/a", LimitMaxError: "1"}, "Ok", "n/a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := channelState(tt.ch)
+			if s.State != tt.state || s.Value != tt.value {
+				t.Errorf("got %v %v, want %v %v", s.State, s.Value, tt.state, tt.value)
+			}
+		})
+	}
+}
+
+func TestMessageText(t *testing.T) {
+	host := func() *prtgData {
+		p := newPrtgData("esx1")
+		p.kind, p.moid = "HostSystem", "host-1"
+		p.cluster, p.power, p.uptime = "prod", "poweredOn", 50*time.Hour
+		p.text = "poweredOn"
+		_ = p.add(0, ps.SensorChannel{Channel: "Connection State", ValueLookup: lookupConnectionState})
+		_ = p.add(2, ps.SensorChannel{Channel: "Triggered Alarms", LimitMaxWarning: "1"})
+		return p
+	}
+	vm := newPrtgData("web1")
+	vm.kind, vm.power, vm.host = "VirtualMachine", "poweredOff", "esx1"
+	ds := newPrtgData("ds1")
+	ds.kind = "Datastore"
+	_ = ds.add(5, ps.SensorChannel{Channel: "free space", LimitMinWarning: "20", LimitMinError: "10"})
+
+	tests := []struct {
+		name string
+		p    *prtgData
+		tmpl string
+		want string
+	}{
+		{"host default", host(), "", "poweredOn in prod, up 2d 2h, Triggered Alarms: 2"},
+		{"vm default", vm, "", "poweredOff on host esx1"},
+		{"datastore default", ds, "", "free space: 5"},
+		{"no type keeps text", &prtgData{text: "switch", items: ds.items}, "", "switch"},
+		{"custom", host(), `{{.Name}} {{.Cluster}} {{index .Channels "Connection State"}} {{with .Worst}}{{.Channel}} {{.State}}{{end}}`, "esx1 prod 0 Triggered Alarms Warning"},
+		{"failing template", host(), `{{.Missing}}`, "poweredOn message template"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.tmpl != "" {
+				m, err := ParseMessage(tt.tmpl)
+				if err != nil {
+					t.Fatal(err)
+				}
+				tt.p.message = m
+			}
+			got := tt.p.messageText()
+			if !strings.HasPrefix(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseMessage("{{.Name"); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestMessageFailures(t *testing.T) {
+	p := newPrtgData("esx1")
+	p.kind, p.power = "HostSystem", "poweredOn"
+	p.failures = []string{"vmhba1:C0:T0:L1", "vmhba2:C0:T0:L1"}
+	want := "poweredOn, path failure vmhba1:C0:T0:L1, vmhba2:C0:T0:L1"
+	if got := p.messageText(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
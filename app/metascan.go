@@ -22,7 +22,6 @@ import (
 	"fmt"
 	"github.com/vmware/govmomi/view"
 	"github.com/vmware/govmomi/vim25/mo"
-	"log"
 	"os"
 	"path/filepath"
 	"sort"
@@ -36,6 +35,7 @@ type Item struct {
 	ID              string `xml:"id,omitempty"`
 	Exefile         string `xml:"exefile"`
 	Params          string `xml:"params"`
+	Environment     string `xml:"environment,omitempty"`
 	Displayname     string `xml:"displayname,attr,omitempty"`
 	Autoacknowledge string `xml:"autoacknowledge,attr,omitempty"`
 }
@@ -53,16 +53,16 @@ type moidNames struct {
 	mu   sync.RWMutex
 }
 
-func newMoidNames(c *Client) *moidNames {
-	m, err := c.getmanagedObjectMap()
+func newMoidNames(ctx context.Context, c *Client) (*moidNames, error) {
+	m, err := c.getmanagedObjectMap(ctx)
 	if err != nil {
-		log.Fatalf("failed to get managed object names")
+		return nil, fmt.Errorf("failed to get managed object names %v", err)
 	}
 	mob := moidNames{
 		moid: m,
 		mu:   sync.RWMutex{},
 	}
-	return &mob
+	return &mob, nil
 }
 
 func (m *moidNames) GetName(moid string) string {
@@ -79,19 +79,18 @@ func (m *moidNames) Gettype(moid string) string {
 
 }
 
-func (c *Client) getmanagedObjectMap() (mobj map[string]managedObject, err error) {
-	ctx := context.Background()
-	m := view.NewManager(c.c)
-
-	v, err := m.CreateContainerView(ctx, c.c.ServiceContent.RootFolder, []string{"ManagedEntity"}, true)
-	if err != nil {
-		return
-	}
-	defer func(ctx context.Context) { _ = v.Destroy(ctx) }(ctx)
-
-	any := []string{"ManagedEntity"}
+func (c *Client) getmanagedObjectMap(ctx context.Context) (mobj map[string]managedObject, err error) {
 	var objs []mo.ManagedEntity
-	err = v.RetrieveWithFilter(ctx, any, []string{"name"}, &objs, nil)
+	err = c.retry(ctx, func() error {
+		v, err := view.NewManager(c.c).CreateContainerView(ctx, c.c.ServiceContent.RootFolder, []string{"ManagedEntity"}, true)
+		if err != nil {
+			return err
+		}
+		defer func(ctx context.Context) { _ = v.Destroy(ctx) }(ctx)
+
+		any := []string{"ManagedEntity"}
+		return v.RetrieveWithFilter(ctx, any, []string{"name"}, &objs, nil)
+	})
 	if err != nil {
 		return
 	}
@@ -107,39 +106,47 @@ func (c *Client) getmanagedObjectMap() (mobj map[string]managedObject, err error
 	return
 }
 
-// Metascan returns template data to PRTG for given tags
-func (c *Client) Metascan(tags []string, tm *TagMap, Age time.Duration) (err error) {
-	for _, tag := range tags {
-		err := c.getObjIds(tag, tm)
-		if err != nil {
-			return err
-		}
+// Metascan returns template data to PRTG for the selected objects
+func (c *Client) Metascan(ctx context.Context, sel Selection, tm *TagMap, Age time.Duration, profile string) (err error) {
+	ctx, cancel := c.inventoryCtx(ctx)
+	defer cancel()
+	err = c.discover(ctx, sel, tm)
+	if err != nil {
+		return PhaseError(ctx, "inventory", err)
+	}
+	moidNames, err := newMoidNames(ctx, c)
+	if err != nil {
+		return PhaseError(ctx, "inventory", err)
 	}
-	moidNames := newMoidNames(c)
 
-	meta, err := c.obMeta(tm, moidNames, Age)
+	meta, err := c.obMeta(tm, moidNames, Age, profile)
 	if err != nil {
 		return fmt.Errorf("objMeta %v", err)
 	}
+	meta.Items, err = c.pageItems(ctx, meta.Items, moidNames)
+	if err != nil {
+		return err
+	}
 
 	if len(meta.Items) == 0 {
-		return fmt.Errorf("no data found for tags %v", tags)
+		return fmt.Errorf("no data found for %v", sel)
 	}
 	output, err := xml.MarshalIndent(meta, "", "   ")
 	if err != nil {
 		return fmt.Errorf("marshal %v", err)
 	}
 
-	fmt.Printf("%+v", string(output))
+	_, err = fmt.Fprintf(c.writer(), "%+v", string(output))
 
 	return
 }
 
-func (c *Client) obMeta(tm *TagMap, moidMap *moidNames, Age time.Duration) (meta prtg, err error) {
+func (c *Client) obMeta(tm *TagMap, moidMap *moidNames, Age time.Duration, profile string) (meta prtg, err error) {
 	meta = prtg{}
 	meta.Items = make([]Item, 0, 10)
 	for id := range tm.Data {
-		creds := fmt.Sprintf("-U https://%%host/sdk -u %%windowsuser -p %%windowspassword --oid %v", id)
+		creds := fmt.Sprintf("%v --oid %v", sensorCreds(profile), id)
+		env := sensorEnv(profile)
 
 		na := moidMap.GetName(id)
 		switch moidMap.Gettype(id) {
@@ -148,8 +155,9 @@ func (c *Client) obMeta(tm *TagMap, moidMap *moidNames, Age time.Duration) (meta
 				Name:            na,
 				ID:              id,
 				Exefile:         filepath.Base(os.Args[0]),
-				Params:          fmt.Sprintf("summary %v --snapAge %v", creds, Age),
+				Params:          fmt.Sprintf("summary%v --snapAge %v", creds, Age),
 				Displayname:     na,
+				Environment:     env,
 				Autoacknowledge: "0",
 			})
 		case "Datastore":
@@ -157,7 +165,8 @@ func (c *Client) obMeta(tm *TagMap, moidMap *moidNames, Age time.Duration) (meta
 				Name:            "DS " + na,
 				ID:              id,
 				Exefile:         filepath.Base(os.Args[0]),
-				Params:          fmt.Sprintf("dsSummary %v", creds),
+				Params:          fmt.Sprintf("dsSummary%v", creds),
+				Environment:     env,
 				Autoacknowledge: "0",
 			})
 		case "HostSystem":
@@ -165,7 +174,8 @@ func (c *Client) obMeta(tm *TagMap, moidMap *moidNames, Age time.Duration) (meta
 				Name:            "Host " + na,
 				ID:              id,
 				Exefile:         filepath.Base(os.Args[0]),
-				Params:          fmt.Sprintf("hsSummary %v", creds),
+				Params:          fmt.Sprintf("hsSummary%v", creds),
+				Environment:     env,
 				Autoacknowledge: "0",
 			})
 		case "VmwareDistributedVirtualSwitch":
@@ -173,10 +183,47 @@ func (c *Client) obMeta(tm *TagMap, moidMap *moidNames, Age time.Duration) (meta
 				Name:            "VDS " + na,
 				ID:              id,
 				Exefile:         filepath.Base(os.Args[0]),
-				Params:          fmt.Sprintf("vdsSummary %v", creds),
+				Params:          fmt.Sprintf("vdsSummary%v", creds),
+				Environment:     env,
+				Autoacknowledge: "0",
+			})
+		case "ClusterComputeResource":
+			meta.Items = append(meta.Items, Item{
+				Name:            "Cluster " + na,
+				ID:              id,
+				Exefile:         filepath.Base(os.Args[0]),
+				Params:          fmt.Sprintf("clusterSummary%v", creds),
+				Environment:     env,
+				Autoacknowledge: "0",
+			})
+		case "ResourcePool":
+			meta.Items = append(meta.Items, Item{
+				Name:            "Pool " + na,
+				ID:              id,
+				Exefile:         filepath.Base(os.Args[0]),
+				Params:          fmt.Sprintf("rpSummary%v", creds),
+				Environment:     env,
+				Autoacknowledge: "0",
+			})
+		case "StoragePod":
+			meta.Items = append(meta.Items, Item{
+				Name:            "Pod " + na,
+				ID:              id,
+				Exefile:         filepath.Base(os.Args[0]),
+				Params:          fmt.Sprintf("podSummary%v", creds),
+				Environment:     env,
+				Autoacknowledge: "0",
+			})
+		case "DistributedVirtualPortgroup":
+			meta.Items = append(meta.Items, Item{
+				Name:            "PG " + na,
+				ID:              id,
+				Exefile:         filepath.Base(os.Args[0]),
+				Params:          fmt.Sprintf("pgSummary%v", creds),
+				Environment:     env,
 				Autoacknowledge: "0",
 			})
-		case "", "ClusterComputeResource", "Folder", "VirtualApp", "Datacenter", "DistributedVirtualPortgroup":
+		case "", "ComputeResource", "Folder", "VirtualApp", "Datacenter", "DistributedVirtualSwitch", "Network":
 		default:
 			fmt.Printf("unsupported type %v\n", moidMap.Gettype(id))
 		}
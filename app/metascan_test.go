@@ -17,6 +17,7 @@
 package app
 
 import (
+	"context"
 	"testing"
 	"time"
 )
@@ -34,14 +35,14 @@ func TestClient_Metascan(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			c, err := NewClient(u, user, passwd, true)
+			c, err := NewClient(context.Background(), u, user, passwd, true, TLSOptions{Insecure: true})
 			if err != nil {
 				t.Fatal("cant get client")
 			}
 			defer func() { _ = c.Logout() }()
 
 			gotRtnMap := NewTagMap()
-			if err := c.Metascan(tt.tags, gotRtnMap, time.Minute); (err != nil) != tt.wantErr {
+			if err := c.Metascan(context.Background(), Selection{Tags: tt.tags}, gotRtnMap, time.Minute, ""); (err != nil) != tt.wantErr {
 				t.Errorf("Metascan() %v, wantErr %v", err, tt.wantErr)
 			}
 			err = c.Logout()
@@ -65,13 +66,16 @@ func TestClient_getObjType(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			c, err := NewClient(u, user, passwd, true)
+			c, err := NewClient(context.Background(), u, user, passwd, true, TLSOptions{Insecure: true})
 			if err != nil {
 				t.Fatal("cant get client")
 			}
 			defer func() { _ = c.Logout() }()
 
-			moi := newMoidNames(&c)
+			moi, err := newMoidNames(context.Background(), &c)
+			if err != nil {
+				t.Fatal(err)
+			}
 			na := moi.GetName(tt.moid)
 			if na == "" {
 				t.Fatalf("could not find %v", na)
@@ -0,0 +1,170 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// udpPacket keeps datagrams below a typical MTU so they aren't fragmented
+const udpPacket = 1400
+
+// Output receives batches of encoded lines
+type Output interface {
+	Send(b []byte) error
+	Close() error
+}
+
+// OpenOutput opens the destination named by dest, "-" or empty is stdout, a path or file:// url appends to a file,
+// tcp:// and udp:// send to a listener and http:// or https:// post each batch, I.E. to the influx /write api
+func OpenOutput(dest string, timeout time.Duration) (Output, error) {
+	if dest == "" || dest == "-" {
+		return writerOutput{os.Stdout}, nil
+	}
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme == "" || len(u.Scheme) == 1 {
+		// no scheme, or a windows drive letter
+		return openFile(dest)
+	}
+	switch u.Scheme {
+	case "file":
+		return openFile(u.Path)
+	case "tcp", "udp":
+		if u.Host == "" {
+			return nil, fmt.Errorf("output %v needs host:port", dest)
+		}
+		return &netOutput{network: u.Scheme, addr: u.Host, timeout: timeout}, nil
+	case "http", "https":
+		return &httpOutput{url: dest, client: &http.Client{Timeout: timeout}}, nil
+	}
+	return nil, fmt.Errorf("unsupported output %v, use -, a file, tcp://, udp://, http:// or https://", dest)
+}
+
+type writerOutput struct {
+	w io.Writer
+}
+
+func (o writerOutput) Send(b []byte) error {
+	_, err := o.w.Write(b)
+	return err
+}
+
+func (o writerOutput) Close() error {
+	if c, ok := o.w.(io.Closer); ok && o.w != os.Stdout {
+		return c.Close()
+	}
+	return nil
+}
+
+func openFile(fn string) (Output, error) {
+	f, err := os.OpenFile(fn, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open output %v", err)
+	}
+	return writerOutput{f}, nil
+}
+
+// netOutput dials on first use and again after a failed send, so a restarted listener is picked up
+type netOutput struct {
+	network, addr string
+	timeout       time.Duration
+	conn          net.Conn
+}
+
+func (o *netOutput) Send(b []byte) error {
+	if o.conn == nil {
+		c, err := net.DialTimeout(o.network, o.addr, o.timeout)
+		if err != nil {
+			return fmt.Errorf("output %v", err)
+		}
+		o.conn = c
+	}
+	if o.timeout > 0 {
+		_ = o.conn.SetWriteDeadline(time.Now().Add(o.timeout))
+	}
+	var err error
+	if o.network == "udp" {
+		err = o.sendPackets(b)
+	} else {
+		_, err = o.conn.Write(b)
+	}
+	if err != nil {
+		_ = o.conn.Close()
+		o.conn = nil
+		return fmt.Errorf("output %v", err)
+	}
+	return nil
+}
+
+// sendPackets splits b on line ends into datagrams of at most udpPacket bytes, longer lines go on their own
+func (o *netOutput) sendPackets(b []byte) error {
+	for len(b) > 0 {
+		n := len(b)
+		if n > udpPacket {
+			n = bytes.LastIndexByte(b[:udpPacket], '\n') + 1
+			if n == 0 {
+				n = bytes.IndexByte(b, '\n') + 1
+				if n == 0 {
+					n = len(b)
+				}
+			}
+		}
+		if _, err := o.conn.Write(b[:n]); err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+func (o *netOutput) Close() error {
+	if o.conn == nil {
+		return nil
+	}
+	return o.conn.Close()
+}
+
+type httpOutput struct {
+	url    string
+	client *http.Client
+}
+
+func (o *httpOutput) Send(b []byte) error {
+	resp, err := o.client.Post(o.url, "text/plain; charset=utf-8", bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("output %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("output %v %v", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func (o *httpOutput) Close() error {
+	return nil
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prtgvmware")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	fn := filepath.Join(dir, "out.lp")
+
+	for _, dest := range []string{fn, "file://" + filepath.ToSlash(fn)} {
+		o, err := OpenOutput(dest, time.Second)
+		if err != nil {
+			t.Fatalf("OpenOutput(%v) %v", dest, err)
+		}
+		if err := o.Send([]byte("a 1\n")); err != nil {
+			t.Fatal(err)
+		}
+		_ = o.Close()
+	}
+	if b, _ := ioutil.ReadFile(fn); string(b) != "a 1\na 1\n" {
+		t.Errorf("file output appends, got %q", b)
+	}
+
+	for _, dest := range []string{"ftp://host/x", "tcp://", "udp:///x"} {
+		if _, err := OpenOutput(dest, time.Second); err == nil {
+			t.Errorf("OpenOutput(%v) expected error", dest)
+		}
+	}
+}
+
+func TestNetOutput(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.Close() }()
+	got := make(chan string, 1)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = c.Close() }()
+		line, _ := bufio.NewReader(c).ReadString('\n')
+		got <- line
+	}()
+	o, err := OpenOutput("tcp://"+l.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Send([]byte("tcp 1\n")); err != nil {
+		t.Fatal(err)
+	}
+	if s := <-got; s != "tcp 1\n" {
+		t.Errorf("tcp got %q", s)
+	}
+	_ = o.Close()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = pc.Close() }()
+	o, err = OpenOutput("udp://"+pc.LocalAddr().String(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = o.Close() }()
+	line := strings.Repeat("x", 99) + "\n"
+	if err := o.Send([]byte(strings.Repeat(line, 30))); err != nil {
+		t.Fatal(err)
+	}
+	_ = pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	total := 0
+	buf := make([]byte, 65536)
+	for total < 3000 {
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("udp read after %v bytes %v", total, err)
+		}
+		if n > udpPacket || !bytes.HasSuffix(buf[:n], []byte("\n")) {
+			t.Errorf("datagram of %v bytes splits a line or is too big", n)
+		}
+		total += n
+	}
+}
+
+func TestHTTPOutput(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		if r.URL.Query().Get("db") != "vmware" {
+			http.Error(w, `{"error":"database not found"}`, http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	o, err := OpenOutput(srv.URL+"/write?db=vmware", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Send([]byte("m v=1\n")); err != nil || body != "m v=1\n" {
+		t.Errorf("Send() %v body %q", err, body)
+	}
+	o, _ = OpenOutput(srv.URL+"/write?db=other", time.Second)
+	if err := o.Send([]byte("m v=1\n")); err == nil || !strings.Contains(err.Error(), "database not found") {
+		t.Errorf("expected influx error, got %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/vmware/govmomi/performance"
+	"github.com/vmware/govmomi/vim25/types"
+	"io"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// how long a pooled session is trusted before checking it again
+	poolCheckInterval = time.Minute
+	// how long name lookups are kept
+	inventoryTTL = 5 * time.Minute
+)
+
+// clientCache holds metadata that rarely changes, shared by all copies of a Client
+type clientCache struct {
+	mu       sync.Mutex
+	perf     *performance.Manager
+	maxQuery int
+	found    map[string]foundObject
+}
+
+type foundObject struct {
+	ref  types.ManagedObjectReference
+	seen time.Time
+}
+
+func newClientCache() *clientCache {
+	return &clientCache{found: make(map[string]foundObject)}
+}
+
+// perfManager returns a performance manager, counter info is only retrieved once per session
+func (c *Client) perfManager() *performance.Manager {
+	if c.cache == nil {
+		return performance.NewManager(c.c)
+	}
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+	if c.cache.perf == nil {
+		c.cache.perf = performance.NewManager(c.c)
+	}
+	return c.cache.perf
+}
+
+func (c *Client) maxQueryMetrics(ctx context.Context) (int, error) {
+	if c.cache == nil {
+		return c.getMaxQueryMetrics(ctx)
+	}
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+	if c.cache.maxQuery == 0 {
+		v, err := c.getMaxQueryMetrics(ctx)
+		if err != nil {
+			return 0, err
+		}
+		c.cache.maxQuery = v
+	}
+	return c.cache.maxQuery, nil
+}
+
+func (c *Client) cachedFind(name, vmwareType string) (types.ManagedObjectReference, bool) {
+	if c.cache == nil {
+		return types.ManagedObjectReference{}, false
+	}
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+	f, ok := c.cache.found[vmwareType+"/"+name]
+	if !ok || time.Since(f.seen) > inventoryTTL {
+		return types.ManagedObjectReference{}, false
+	}
+	return f.ref, true
+}
+
+func (c *Client) storeFind(name, vmwareType string, ref types.ManagedObjectReference) {
+	if c.cache == nil {
+		return
+	}
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+	c.cache.found[vmwareType+"/"+name] = foundObject{ref: ref, seen: time.Now()}
+}
+
+// SetOutput sets where sensor results are written, defaults to stdout
+func (c *Client) SetOutput(w io.Writer) {
+	c.out = w
+}
+
+func (c *Client) writer() io.Writer {
+	if c.out == nil {
+		return os.Stdout
+	}
+	return c.out
+}
+
+// Pool keeps one logged in client per vcenter and user for long running processes
+type Pool struct {
+	mu      sync.Mutex
+	clients map[string]*pooledClient
+	cache   bool
+}
+
+type pooledClient struct {
+	mu      sync.Mutex
+	c       Client
+	checked time.Time
+}
+
+// NewPool creates an empty pool, cache controls whether sessions are also saved to disk
+func NewPool(cache bool) *Pool {
+	return &Pool{clients: make(map[string]*pooledClient), cache: cache}
+}
+
+// Get returns a logged in client, logging in again if the pooled session has expired.
+// Returned clients are marked cached so callers don't log the shared session out
+func (p *Pool) Get(ctx context.Context, u *url.URL, user, pw string, tlsOpts TLSOptions) (Client, error) {
+	// password is part of the key so a wrong password never gets a pooled session,
+	// tls settings so a session is never reused by a request that would fail verification
+	sum := sha256.Sum256([]byte(u.String() + "\x00" + user + "\x00" + pw + "\x00" + tlsOpts.String()))
+	key := hex.EncodeToString(sum[:])
+
+	p.mu.Lock()
+	pc, ok := p.clients[key]
+	if !ok {
+		pc = &pooledClient{}
+		p.clients[key] = pc
+	}
+	p.mu.Unlock()
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.c.c != nil && time.Since(pc.checked) < poolCheckInterval {
+		return pc.shared(), nil
+	}
+	if pc.c.c != nil && sessionCheck(ctx, pc.c.c) == nil {
+		pc.checked = time.Now()
+		return pc.shared(), nil
+	}
+
+	lu := *u
+	c, err := NewClient(ctx, &lu, user, pw, p.cache, tlsOpts)
+	if err != nil {
+		return Client{}, err
+	}
+	pc.c = c
+	pc.checked = time.Now()
+	return pc.shared(), nil
+}
+
+func (pc *pooledClient) shared() Client {
+	c := pc.c
+	c.Cached = true
+	c.pooled = pc
+	return c
+}
+
+// Close logs out sessions that were not saved to disk
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for k, pc := range p.clients {
+		if pc.c.c != nil && !p.cache {
+			_ = pc.c.Logout()
+		}
+		delete(p.clients, k)
+	}
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"fmt"
+	ps "github.com/PRTG/go-prtg-sensor-api"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"strings"
+	"time"
+)
+
+// PortgroupSummary stats for a distributed portgroup, traffic is summed over the ports in the portgroup
+func (c *Client) PortgroupSummary(ctx context.Context, name, moid string, js bool) (err error) {
+	start := time.Now()
+	ictx, cancel := c.inventoryCtx(ctx)
+	defer cancel()
+
+	id := types.ManagedObjectReference{
+		Type:  "DistributedVirtualPortgroup",
+		Value: moid,
+	}
+	if moid == "" {
+		id, err = c.findOne(ictx, name, id.Type)
+		if err != nil {
+			return PhaseError(ictx, "inventory", err)
+		}
+	}
+	pg := mo.DistributedVirtualPortgroup{}
+	err = c.retrieveOne(ictx, id, []string{"name", "key", "config", "portKeys", "vm", "overallStatus", "triggeredAlarmState"}, &pg)
+	if err != nil {
+		return PhaseError(ictx, "inventory", errCheck(name, id, fmt.Errorf("portgroup v.properties %v", err)))
+	}
+	ports, err := c.portgroupPorts(ictx, pg)
+	if err != nil {
+		return PhaseError(ictx, "inventory", errCheck(name, id, err))
+	}
+
+	pr := c.prtgData(id, pg.Name)
+	_ = pr.add(managedEntityStatus(pg.OverallStatus), ps.SensorChannel{Channel: "Overall Status", Unit: "Custom", CustomUnit: "Custom", ValueLookup: lookupStatus})
+	_ = pr.add(triggeredAlarms(pg.TriggeredAlarmState), ps.SensorChannel{Channel: "Triggered Alarms", Unit: "Count", LimitMaxWarning: "1", LimitWarningMsg: "triggered alarms present"})
+
+	if s, ok := pg.Config.DefaultPortConfig.(*types.VMwareDVSPortSetting); ok && s.Vlan != nil {
+		switch v := s.Vlan.(type) {
+		case *types.VmwareDistributedVirtualSwitchVlanIdSpec:
+			_ = pr.add(v.VlanId, ps.SensorChannel{Channel: "VLAN ID", Unit: "Custom", CustomUnit: "VLAN"})
+			pr.text = fmt.Sprintf("VLAN %v", v.VlanId)
+		case *types.VmwareDistributedVirtualSwitchPvlanSpec:
+			_ = pr.add(v.PvlanId, ps.SensorChannel{Channel: "VLAN ID", Unit: "Custom", CustomUnit: "VLAN"})
+			pr.text = fmt.Sprintf("private VLAN %v", v.PvlanId)
+		case *types.VmwareDistributedVirtualSwitchTrunkVlanSpec:
+			n, r := trunkVlans(v.VlanId)
+			_ = pr.add(n, ps.SensorChannel{Channel: "Trunk VLANs", Unit: "Count"})
+			pr.text = "trunk " + r
+		}
+	}
+
+	var inUse, blocked int
+	var bytesIn, bytesOut, packetsIn, packetsOut, droppedIn, droppedOut int64
+	for _, p := range ports {
+		if p.Connectee != nil {
+			inUse++
+		}
+		if portBlocked(p) {
+			blocked++
+		}
+		if p.State != nil {
+			s := p.State.Stats
+			bytesIn += s.BytesInUnicast + s.BytesInMulticast + s.BytesInBroadcast
+			bytesOut += s.BytesOutUnicast + s.BytesOutMulticast + s.BytesOutBroadcast
+			packetsIn += s.PacketsInUnicast + s.PacketsInMulticast + s.PacketsInBroadcast
+			packetsOut += s.PacketsOutUnicast + s.PacketsOutMulticast + s.PacketsOutBroadcast
+			droppedIn += s.PacketsInDropped
+			droppedOut += s.PacketsOutDropped
+		}
+	}
+	_ = pr.add(pg.Config.NumPorts, ps.SensorChannel{Channel: "Ports Configured", Unit: "Count"})
+	_ = pr.add(inUse, ps.SensorChannel{Channel: "Ports In Use", Unit: "Count"})
+	if pg.Config.NumPorts > 0 {
+		ch := ps.SensorChannel{Channel: "Ports In Use (Percent)", Unit: "Percent"}
+		// portgroups that grow on demand never run out of ports
+		if !isTrue(pg.Config.AutoExpand) {
+			ch.LimitMaxWarning, ch.LimitMaxError, ch.LimitWarningMsg, ch.LimitErrorMsg, ch.LimitMode = "80", "95", "few free ports", "out of ports", "1"
+		}
+		_ = pr.add(float64(inUse)*100/float64(pg.Config.NumPorts), ch)
+	}
+	_ = pr.add(len(pg.Vm), ps.SensorChannel{Channel: "Connected VMs", Unit: "Count"})
+	_ = pr.add(blocked, ps.SensorChannel{Channel: "Blocked Ports", Unit: "Count", LimitMaxWarning: "0", LimitWarningMsg: "blocked ports", LimitMode: "1"})
+
+	// port statistics count up from when the port was created, PRTG shows the difference between scans
+	_ = pr.add(bytesIn, ps.SensorChannel{Channel: "Bytes In", Unit: "BytesBandwidth", ValueMode: "Difference"})
+	_ = pr.add(bytesOut, ps.SensorChannel{Channel: "Bytes Out", Unit: "BytesBandwidth", ValueMode: "Difference"})
+	_ = pr.add(packetsIn, ps.SensorChannel{Channel: "Packets In", Unit: "Count", ValueMode: "Difference"})
+	_ = pr.add(packetsOut, ps.SensorChannel{Channel: "Packets Out", Unit: "Count", ValueMode: "Difference"})
+	_ = pr.add(droppedIn, ps.SensorChannel{Channel: "Packets In Dropped", Unit: "Count", ValueMode: "Difference"})
+	_ = pr.add(droppedOut, ps.SensorChannel{Channel: "Packets Out Dropped", Unit: "Count", ValueMode: "Difference"})
+
+	_ = pr.print(time.Since(start), js)
+	return nil
+}
+
+// portgroupPorts fetches the ports of pg from its switch, only ports listed by the portgroup are kept
+// as not every server filters by portgroup
+func (c *Client) portgroupPorts(ctx context.Context, pg mo.DistributedVirtualPortgroup) ([]types.DistributedVirtualPort, error) {
+	if pg.Config.DistributedVirtualSwitch == nil || len(pg.PortKeys) == 0 {
+		return nil, nil
+	}
+	criteria := &types.DistributedVirtualSwitchPortCriteria{PortgroupKey: []string{pg.Key}, PortKey: pg.PortKeys}
+	var ports []types.DistributedVirtualPort
+	err := c.retry(ctx, func() (err error) {
+		ports, err = object.NewDistributedVirtualSwitch(c.c, *pg.Config.DistributedVirtualSwitch).FetchDVPorts(ctx, criteria)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("portgroup ports %v", err)
+	}
+	keys := make(map[string]bool, len(pg.PortKeys))
+	for _, k := range pg.PortKeys {
+		keys[k] = true
+	}
+	rtn := ports[:0]
+	for _, p := range ports {
+		if keys[p.Key] && (p.PortgroupKey == "" || p.PortgroupKey == pg.Key) {
+			rtn = append(rtn, p)
+		}
+	}
+	return rtn, nil
+}
+
+// portBlocked is the runtime state of a port, falling back to its configured policy
+func portBlocked(p types.DistributedVirtualPort) bool {
+	if p.State != nil && p.State.RuntimeInfo != nil {
+		return p.State.RuntimeInfo.Blocked
+	}
+	if s, ok := p.Config.Setting.(*types.VMwareDVSPortSetting); ok && s.Blocked != nil {
+		return isTrue(s.Blocked.Value)
+	}
+	return false
+}
+
+// trunkVlans is the number of vlans a trunk carries and its ranges, I.E 10-20, 30
+func trunkVlans(r []types.NumericRange) (int32, string) {
+	var n int32
+	s := make([]string, 0, len(r))
+	for _, v := range r {
+		n += v.End - v.Start + 1
+		if v.Start == v.End {
+			s = append(s, fmt.Sprint(v.Start))
+			continue
+		}
+		s = append(s, fmt.Sprintf("%v-%v", v.Start, v.End))
+	}
+	return n, strings.Join(s, ", ")
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPortgroupSummary(t *testing.T) {
+	c, stop := newSimClient(t, nil)
+	defer stop()
+	ctx := context.Background()
+
+	var got Result
+	c.SetSink(func(r Result) error {
+		got = r
+		return nil
+	})
+	err := c.PortgroupSummary(ctx, "DC0_DVPG0", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]string)
+	for _, s := range got.Samples {
+		values[s.Channel] = s.Value
+	}
+	for _, ch := range []string{"Overall Status", "Ports Configured", "Ports In Use", "Connected VMs", "Blocked Ports", "Bytes In", "Packets Out Dropped"} {
+		if _, ok := values[ch]; !ok {
+			t.Errorf("no %v in %v", ch, values)
+		}
+	}
+	if values["Blocked Ports"] != "0" {
+		t.Errorf("blocked ports %v", values["Blocked Ports"])
+	}
+
+	finder := find.NewFinder(c.c, true)
+	dc, err := finder.Datacenter(ctx, "DC0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	finder.SetDatacenter(dc)
+
+	pg, err := finder.Network(ctx, "DC0_DVPG0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ports of a blocked trunk portgroup
+	var cfg mo.DistributedVirtualPortgroup
+	if err := c.retrieveOne(ctx, pg.Reference(), []string{"config"}, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	dvs := object.NewDistributedVirtualSwitch(c.c, *cfg.Config.DistributedVirtualSwitch)
+	task, err := dvs.AddPortgroup(ctx, []types.DVPortgroupConfigSpec{{
+		Name:     "trunk0",
+		NumPorts: 4,
+		DefaultPortConfig: &types.VMwareDVSPortSetting{
+			DVPortSetting: types.DVPortSetting{Blocked: &types.BoolPolicy{Value: types.NewBool(true)}},
+			Vlan:          &types.VmwareDistributedVirtualSwitchTrunkVlanSpec{VlanId: []types.NumericRange{{Start: 100, End: 109}}},
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := task.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	err = c.PortgroupSummary(ctx, "trunk0", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values = make(map[string]string)
+	for _, s := range got.Samples {
+		values[s.Channel] = s.Value
+	}
+	if values["Ports Configured"] != "4" || values["Blocked Ports"] != "4" || values["Trunk VLANs"] != "10" || values["Ports In Use"] != "0" {
+		t.Errorf("trunk channels %v", values)
+	}
+	if got.Text != "trunk 100-109, Blocked Ports: 4" {
+		t.Errorf("text %q", got.Text)
+	}
+
+	host, err := finder.HostSystem(ctx, "DC0_H0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// portgroups reached through a host or the network folder of a datacenter are left out, a tag on the portgroup selects it
+	for _, parent := range []types.ManagedObjectReference{host.Reference(), dc.Reference()} {
+		ids, err := c.getChildIds(ctx, parent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switches := 0
+		for _, id := range ids {
+			switch id.Type {
+			case "DistributedVirtualPortgroup":
+				t.Errorf("%v children include portgroup %v", parent.Type, id)
+			case "VmwareDistributedVirtualSwitch", "DistributedVirtualSwitch":
+				switches++
+			}
+		}
+		if parent.Type == "Datacenter" && switches == 0 {
+			t.Errorf("datacenter children %v have no switch", ids)
+		}
+	}
+	tm := NewTagMap()
+	tm.add(pg.Reference(), "network")
+	names, err := newMoidNames(ctx, &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := c.obMeta(tm, names, time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the simulator keeps portgroup names outside ManagedEntity so only the prefix is checked
+	if len(meta.Items) != 1 || !strings.HasPrefix(meta.Items[0].Name, "PG ") || !strings.HasPrefix(meta.Items[0].Params, "pgSummary") {
+		t.Errorf("items %+v", meta.Items)
+	}
+}
+
+func TestTrunkVlans(t *testing.T) {
+	tests := []struct {
+		name   string
+		ranges []types.NumericRange
+		count  int32
+		text   string
+	}{
+		{"single", []types.NumericRange{{Start: 10, End: 10}}, 1, "10"},
+		{"ranges", []types.NumericRange{{Start: 10, End: 20}, {Start: 30, End: 30}}, 12, "10-20, 30"},
+		{"all", []types.NumericRange{{Start: 0, End: 4094}}, 4095, "0-4094"},
+		{"none", nil, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, s := trunkVlans(tt.ranges)
+			if n != tt.count || s != tt.text {
+				t.Errorf("trunkVlans() = %v %q, want %v %q", n, s, tt.count, tt.text)
+			}
+		})
+	}
+}
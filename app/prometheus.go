@@ -0,0 +1,222 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const promContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Exporter serves summaries of the selected objects in the Prometheus text format
+type Exporter struct {
+	// Login returns a logged in client for each collection
+	Login     func(ctx context.Context) (Client, error)
+	VCenter   string
+	Selection Selection
+	Options   CollectOptions
+	// Timeout bounds a single collection
+	Timeout time.Duration
+
+	// scrapeMu serialises collections so slow scrapes don't pile up against vcenter
+	scrapeMu sync.Mutex
+	mu       sync.Mutex
+	// last holds the latest collection when running on an interval
+	last     []byte
+	interval bool
+}
+
+// Run collects every interval until ctx is done, scrapes are then answered from the latest collection
+func (e *Exporter) Run(ctx context.Context, interval time.Duration) {
+	e.mu.Lock()
+	e.interval = true
+	e.mu.Unlock()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		b := e.Scrape(ctx)
+		e.mu.Lock()
+		e.last = b
+		e.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// ServeHTTP answers a scrape
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	interval, b := e.interval, e.last
+	e.mu.Unlock()
+	if !interval {
+		b = e.Scrape(r.Context())
+	}
+	if b == nil {
+		http.Error(w, "first collection has not finished", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", promContentType)
+	_, _ = w.Write(b)
+}
+
+// Scrape runs a collection and returns it in the exposition format, failures are reported by vmware_up
+func (e *Exporter) Scrape(ctx context.Context) []byte {
+	e.scrapeMu.Lock()
+	defer e.scrapeMu.Unlock()
+	ctx, cancel := WithBudget(ctx, e.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	fams := newPromFamilies()
+	err := e.collect(ctx, fams)
+	up := 1
+	if err != nil {
+		log.Printf("exporter collection %v", err)
+		up = 0
+	}
+	vc := promLabels{{"vcenter", e.VCenter}}
+	fams.add("vmware_up", "1 if the last collection from vcenter succeeded", vc, float64(up))
+	fams.add("vmware_collect_duration_seconds", "time taken by the last collection", vc, time.Since(start).Seconds())
+
+	buf := &bytes.Buffer{}
+	_ = fams.write(buf)
+	return buf.Bytes()
+}
+
+func (e *Exporter) collect(ctx context.Context, fams promFamilies) error {
+	c, err := e.Login(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if !c.Cached {
+			_ = c.Logout()
+		}
+	}()
+	return c.Collect(ctx, e.Selection, e.Options, func(r Result) error {
+		fams.addResult(e.VCenter, r)
+		return nil
+	})
+}
+
+type promLabels [][2]string
+
+func (l promLabels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+	s := make([]string, 0, len(l))
+	for _, kv := range l {
+		s = append(s, kv[0]+`="`+promEscape(kv[1], true)+`"`)
+	}
+	return "{" + strings.Join(s, ",") + "}"
+}
+
+// promEscape escapes label values, or help text which leaves quotes alone
+func promEscape(s string, quote bool) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	if quote {
+		s = strings.Replace(s, `"`, `\"`, -1)
+	}
+	return s
+}
+
+type promFamily struct {
+	help  string
+	lines map[string]float64
+}
+
+// promFamilies groups samples by metric name, series repeated by an object are kept once
+type promFamilies map[string]*promFamily
+
+func newPromFamilies() promFamilies {
+	return make(promFamilies)
+}
+
+func (f promFamilies) add(name, help string, l promLabels, v float64) {
+	fam, ok := f[name]
+	if !ok {
+		fam = &promFamily{help: help, lines: make(map[string]float64)}
+		f[name] = fam
+	}
+	series := name + l.String()
+	if _, dup := fam.lines[series]; !dup {
+		fam.lines[series] = v
+	}
+}
+
+// addResult maps every channel of r to a family named after the object type and what the channel measures
+func (f promFamilies) addResult(vcenter string, r Result) {
+	obj := promLabels{{"vcenter", vcenter}, {"type", r.Type}, {"moid", r.Moid}, {"name", r.Name}}
+	failed := 0.0
+	if r.Err != "" {
+		failed = 1
+	}
+	f.add("vmware_object_collect_error", "1 if the object summary failed", obj, failed)
+	if r.Err == "" {
+		f.add("vmware_object_collect_duration_seconds", "time taken to read the object inventory", obj, r.Duration.Seconds())
+	}
+	for _, s := range r.Samples {
+		v, ok := s.Float()
+		if !ok {
+			continue
+		}
+		name := "vmware_" + metricName(shortType(r.Type)+" "+s.Key())
+		f.add(name, s.Key(), append(obj[:len(obj):len(obj)], [2]string{"instance", s.Instance}), v)
+	}
+}
+
+func (f promFamilies) write(w io.Writer) error {
+	names := make([]string, 0, len(f))
+	for n := range f {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fam := f[n]
+		_, err := fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v gauge\n", n, promEscape(fam.help, false), n)
+		if err != nil {
+			return err
+		}
+		series := make([]string, 0, len(fam.lines))
+		for s := range fam.lines {
+			series = append(series, s)
+		}
+		sort.Strings(series)
+		for _, s := range series {
+			_, err = fmt.Fprintf(w, "%v %v\n", s, strconv.FormatFloat(fam.lines[s], 'g', -1, 64))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
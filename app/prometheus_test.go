@@ -0,0 +1,121 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	ps "github.com/PRTG/go-prtg-sensor-api"
+	"github.com/vmware/govmomi/simulator"
+	_ "github.com/vmware/govmomi/vapi/simulator"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"vm cpu.usage.average", "vm_cpu_usage_average"},
+		{"host Memory Free (Percent)", "host_memory_free_percent"},
+		{"vm guest disk free bytes", "vm_guest_disk_free_bytes"},
+		{"--Odd  name--", "odd_name"},
+	}
+	for _, tt := range tests {
+		if got := metricName(tt.in); got != tt.want {
+			t.Errorf("metricName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPromFamilies(t *testing.T) {
+	f := newPromFamilies()
+	f.addResult("vc1", Result{Type: "VirtualMachine", Moid: "vm-1", Name: `web "1"`, Duration: time.Second, Samples: []Sample{
+		{SensorChannel: ps.SensorChannel{Channel: "cpu.usage.average", Value: "12.50"}, Family: "cpu.usage.average"},
+		{SensorChannel: ps.SensorChannel{Channel: "free Bytes /", Value: "100"}, Family: "guest disk free bytes", Instance: "/"},
+		{SensorChannel: ps.SensorChannel{Channel: "free Bytes /boot", Value: "50"}, Family: "guest disk free bytes", Instance: "/boot"},
+		{SensorChannel: ps.SensorChannel{Channel: "text", Value: "n/a"}},
+	}})
+	f.addResult("vc1", Result{Type: "HostSystem", Moid: "host-1", Name: "esx1", Err: "timed out"})
+	buf := &bytes.Buffer{}
+	if err := f.write(buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, s := range []string{
+		"# HELP vmware_vm_cpu_usage_average cpu.usage.average\n# TYPE vmware_vm_cpu_usage_average gauge\n",
+		`vmware_vm_cpu_usage_average{vcenter="vc1",type="VirtualMachine",moid="vm-1",name="web \"1\"",instance=""} 12.5`,
+		`vmware_vm_guest_disk_free_bytes{vcenter="vc1",type="VirtualMachine",moid="vm-1",name="web \"1\"",instance="/"} 100`,
+		`vmware_vm_guest_disk_free_bytes{vcenter="vc1",type="VirtualMachine",moid="vm-1",name="web \"1\"",instance="/boot"} 50`,
+		`vmware_object_collect_error{vcenter="vc1",type="HostSystem",moid="host-1",name="esx1"} 1`,
+		`vmware_object_collect_duration_seconds{vcenter="vc1",type="VirtualMachine",moid="vm-1",name="web \"1\""} 1`,
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("write() missing %v\n%v", s, out)
+		}
+	}
+	if strings.Contains(out, "vmware_vm_text") {
+		t.Errorf("non numeric channel exported\n%v", out)
+	}
+}
+
+func TestExporter(t *testing.T) {
+	s, stop := newSim(t, simulator.VPX(), nil)
+	defer stop()
+
+	e := &Exporter{
+		Login: func(ctx context.Context) (Client, error) {
+			return NewClient(ctx, s.URL, "user", "pass", false, TLSOptions{Insecure: true})
+		},
+		VCenter:   "vc1",
+		Selection: Selection{Folders: []string{"/DC0"}},
+		Options:   CollectOptions{SnapAge: time.Hour},
+		Timeout:   time.Minute,
+	}
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	buf := &bytes.Buffer{}
+	_, _ = buf.ReadFrom(resp.Body)
+	out := buf.String()
+
+	if ct := resp.Header.Get("Content-Type"); ct != promContentType {
+		t.Errorf("content type %v", ct)
+	}
+	for _, s := range []string{
+		`vmware_up{vcenter="vc1"} 1`,
+		`vmware_host_cpu_capacity_mhz{vcenter="vc1",type="HostSystem",moid="host-21",name="DC0_H0",instance=""}`,
+		`vmware_vm_guest_tools_running{vcenter="vc1",type="VirtualMachine",moid="vm-54",name="DC0_H0_VM0",instance=""}`,
+		`vmware_datastore_total_capacity{vcenter="vc1",type="Datastore"`,
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("scrape missing %v\n%v", s, out)
+		}
+	}
+
+	e.Login = func(ctx context.Context) (Client, error) { return Client{}, fmt.Errorf("refused") }
+	if out := string(e.Scrape(context.Background())); !strings.Contains(out, `vmware_up{vcenter="vc1"} 0`) {
+		t.Errorf("failed scrape %v", out)
+	}
+}
@@ -17,12 +17,16 @@
 package app
 
 import (
-	"encoding/json"
 	"fmt"
 	ps "github.com/PRTG/go-prtg-sensor-api"
+	"github.com/vmware/govmomi/vim25/types"
+	"io"
 	"log"
+	"os"
 	"sort"
+	"strings"
 	"sync"
+	"text/template"
 	"time"
 )
 
@@ -32,12 +36,28 @@ type LimitsStruct struct {
 }
 
 type prtgData struct {
-	mu    *sync.RWMutex
-	name  string
-	moid  string
-	err   string
-	text  string
-	items []ps.SensorChannel
+	mu     *sync.RWMutex
+	out    io.Writer
+	format Format
+	limit  ChannelLimit
+	names  *ChannelNames
+	sink   func(Result) error
+	kind   string
+	name   string
+	moid   string
+	err    string
+	text   string
+	items  []ps.SensorChannel
+	meta   map[string]sampleMeta
+	// dups are channel names that were repeated, later channels got a counter
+	dups []string
+	// message renders text, host and the fields below are only used by message templates
+	message  *template.Template
+	host     string
+	cluster  string
+	power    string
+	uptime   time.Duration
+	failures []string
 }
 
 func newPrtgData(name string) *prtgData {
@@ -48,7 +68,26 @@ func newPrtgData(name string) *prtgData {
 	return &p
 }
 
+// prtgData returns result data for ref written in the output and format set on the client
+func (c *Client) prtgData(ref types.ManagedObjectReference, name string) *prtgData {
+	p := newPrtgData(name)
+	p.kind = ref.Type
+	p.moid = ref.Value
+	p.out = c.out
+	p.format = c.format
+	p.limit = c.limit
+	p.names = c.names
+	p.sink = c.sink
+	p.message = c.message
+	return p
+}
+
 func (p *prtgData) add(value interface{}, item ps.SensorChannel) (err error) {
+	return p.addSample(value, item, "", "")
+}
+
+// addSample adds a channel that exporters group by family and instance rather than channel name
+func (p *prtgData) addSample(value interface{}, item ps.SensorChannel, family, instance string) error {
 	switch value.(type) {
 	case float64:
 		item.Value = fmt.Sprintf("%0.2f", value)
@@ -61,17 +100,35 @@ func (p *prtgData) add(value interface{}, item ps.SensorChannel) (err error) {
 		item.DecimalMode = "1"
 	}
 
+	name := p.names.name(item.Channel)
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	if p.meta == nil {
+		p.meta = make(map[string]sampleMeta)
+	}
+	item.Channel = uniqueChannel(name, func(s string) bool {
+		_, ok := p.meta[s]
+		return ok
+	})
+	if item.Channel != name {
+		p.dups = append(p.dups, name)
+	}
+	p.meta[item.Channel] = sampleMeta{family: family, instance: instance}
 	p.items = append(p.items, item)
-	return
+	return nil
 }
 
 func (p *prtgData) print(checkTime time.Duration, txt bool) error {
+	w := p.out
+	if w == nil {
+		w = os.Stdout
+	}
 
-	s := ps.New()
+	if p.sink != nil {
+		return p.sink(p.result(checkTime))
+	}
 	if p.err != "" {
-		SensorWarn(fmt.Errorf("%v", p.err), true)
+		SensorWarnTo(w, p.format, fmt.Errorf("%v", p.err), true)
 
 		return fmt.Errorf("error state %v", p.err)
 	}
@@ -86,53 +143,48 @@ func (p *prtgData) print(checkTime time.Duration, txt bool) error {
 		return p.items[i].Channel <= p.items[j].Channel
 	})
 
-	for _, item := range p.items {
-		sc := s.AddChannel(item.Channel)
-		*sc = item
+	items, note, err := p.limit.apply(p.items, p.meta)
+	if err != nil {
+		SensorWarnTo(w, p.format, err, true)
+		return err
 	}
-
-	s.SetSensorText(p.text)
-	s.SetError(false)
+	if len(p.dups) > 0 {
+		note = strings.TrimSpace(fmt.Sprintf("%v duplicate channel names %v", note, strings.Join(p.dups, ", ")))
+	}
+	r := sensorResult{text: strings.TrimSpace(p.messageText() + " " + note)}
+	r.channels = append(r.channels, items...)
 
 	// Response time channel
-	s.AddChannel("Execution time").SetValue(checkTime.Seconds() * 1000).SetUnit(ps.TimeResponse)
+	rt := ps.SensorChannel{Channel: "Execution time"}
+	rt.SetValue(checkTime.Seconds() * 1000).SetUnit(ps.TimeResponse)
+	r.channels = append(r.channels, rt)
 
-	if !txt {
-		js, err := s.MarshalToString()
-		if err != nil {
-			return fmt.Errorf("prtgdata.print marshall to string %v", err)
-		}
-
-		fmt.Println(js)
-		return nil
+	if txt {
+		_, _ = fmt.Fprintln(w, p.name, p.moid)
 	}
-
-	b, err := json.MarshalIndent(s, "", "    ")
+	err = r.encode(w, p.format, txt)
 	if err != nil {
-		return err
+		return fmt.Errorf("prtgdata.print %v", err)
 	}
-	fmt.Println(p.name, p.moid)
-	fmt.Printf("%+v\n", string(b))
-
 	return nil
 }
 
-//SensorWarn is used to return an error via PRTG message
+// SensorWarn is used to return an error via PRTG message
 func SensorWarn(inErr error, er bool) {
-	s := ps.New()
-	if er {
-		s.SetError(true)
-	} else {
-		s.SetError(false)
-		c := s.AddChannel("Execution time").SetValue(999).SetUnit(ps.TimeResponse)
-		c.Warning = "1"
+	SensorWarnTo(os.Stdout, FormatJSON, inErr, er)
+}
 
+// SensorWarnTo writes a PRTG error or warning message to w in format f
+func SensorWarnTo(w io.Writer, f Format, inErr error, er bool) {
+	r := sensorResult{text: inErr.Error(), err: er}
+	if !er {
+		c := ps.SensorChannel{Channel: "Execution time"}
+		c.SetValue(999).SetUnit(ps.TimeResponse)
+		c.Warning = "1"
+		r.channels = append(r.channels, c)
 	}
-	s.SetSensorText(inErr.Error())
-	js, err := s.MarshalToString()
+	err := r.encode(w, f, false)
 	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Println(js)
-
 }
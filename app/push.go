@@ -0,0 +1,241 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	ps "github.com/PRTG/go-prtg-sensor-api"
+	"github.com/vmware/govmomi/vim25/types"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PushToken identifies the HTTP Push Data Advanced sensor receiving an object's summary
+type PushToken struct {
+	Token string `yaml:"token"`
+	Type  string `yaml:"type"`
+	Name  string `yaml:"name"`
+}
+
+// PushTokens maps object ids to push sensor tokens, it is written by dynamicTemplates --push and
+// doubles as the list of objects to push when no selection is given
+type PushTokens struct {
+	// URL is the probe push endpoint, I.E http://probe:5050
+	URL     string               `yaml:"url"`
+	Objects map[string]PushToken `yaml:"objects"`
+}
+
+// LoadPushTokens reads a token file, a missing file returns an empty mapping
+func LoadPushTokens(fn string) (*PushTokens, error) {
+	t := &PushTokens{Objects: make(map[string]PushToken)}
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return t, nil
+		}
+		return nil, fmt.Errorf("read push tokens %v", err)
+	}
+	err = yaml.UnmarshalStrict(b, t)
+	if err != nil {
+		return nil, fmt.Errorf("parse push tokens %v %v", fn, err)
+	}
+	if t.Objects == nil {
+		t.Objects = make(map[string]PushToken)
+	}
+	return t, nil
+}
+
+// Save writes the mapping readable only by the current user, tokens let anyone post sensor results
+func (t *PushTokens) Save(fn string) error {
+	b, err := yaml.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fn, b, 0600)
+}
+
+// token returns the token for an object, creating one the first time the object is seen
+func (t *PushTokens) token(moid, kind, name string) (string, error) {
+	pt, ok := t.Objects[moid]
+	if !ok {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		pt.Token = strings.ToUpper(hex.EncodeToString(b))
+	}
+	pt.Type, pt.Name = kind, name
+	t.Objects[moid] = pt
+	return pt.Token, nil
+}
+
+// Selection returns the objects listed in the mapping
+func (t *PushTokens) Selection() Selection {
+	ids := make([]string, 0, len(t.Objects))
+	for id := range t.Objects {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	sel := Selection{}
+	for _, id := range ids {
+		sel.Refs = append(sel.Refs, types.ManagedObjectReference{Type: t.Objects[id].Type, Value: id})
+	}
+	return sel
+}
+
+// Pusher posts results to PRTG HTTP Push Data Advanced sensors
+type Pusher struct {
+	Tokens *PushTokens
+	Client *http.Client
+	// Limit reduces results with more channels than a sensor accepts, as for summaries written to stdout
+	Limit ChannelLimit
+}
+
+// pushResponse is the reply of the PRTG push receiver
+type pushResponse struct {
+	Status   string `json:"status"`
+	Matching string `json:"Matching Sensors"`
+}
+
+// Push sends r to the sensor holding its token, objects without a token are an error
+func (p *Pusher) Push(ctx context.Context, r Result) error {
+	pt, ok := p.Tokens.Objects[r.Moid]
+	if !ok || pt.Token == "" {
+		return fmt.Errorf("no push token for %v %v, rerun dynamicTemplates --push", r.Type, r.Moid)
+	}
+	if p.Tokens.URL == "" {
+		return fmt.Errorf("no push url, set url in the token file or use --pushUrl")
+	}
+	body := &bytes.Buffer{}
+	err := r.sensorResult(p.Limit).encode(body, FormatJSON, false)
+	if err != nil {
+		return err
+	}
+
+	u := strings.TrimSuffix(p.Tokens.URL, "/") + "/" + pt.Token
+	req, err := http.NewRequest(http.MethodPost, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	cl := p.Client
+	if cl == nil {
+		cl = http.DefaultClient
+	}
+	resp, err := cl.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("push %v %v", r.Name, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("push %v %v %v", r.Name, resp.Status, strings.TrimSpace(string(b)))
+	}
+	pr := pushResponse{}
+	if json.Unmarshal(b, &pr) == nil && pr.Matching == "0" {
+		return fmt.Errorf("push %v no sensor has token %v", r.Name, pt.Token)
+	}
+	return nil
+}
+
+// sensorResult converts r back to the PRTG result the summary sensor would have written, reduced by l
+func (r Result) sensorResult(l ChannelLimit) sensorResult {
+	if r.Err != "" {
+		return sensorResult{text: r.Err, err: true}
+	}
+	items := make([]ps.SensorChannel, 0, len(r.Samples))
+	meta := make(map[string]sampleMeta, len(r.Samples))
+	for _, v := range r.Samples {
+		items = append(items, v.SensorChannel)
+		meta[v.Channel] = sampleMeta{family: v.Family, instance: v.Instance}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Channel < items[j].Channel
+	})
+	items, note, err := l.apply(items, meta)
+	if err != nil {
+		return sensorResult{text: err.Error(), err: true}
+	}
+	s := sensorResult{text: strings.TrimSpace(r.Text + " " + note), channels: make([]ps.SensorChannel, 0, len(items)+1)}
+	s.channels = append(s.channels, items...)
+	rt := ps.SensorChannel{Channel: "Execution time"}
+	rt.SetValue(r.Duration.Seconds() * 1000).SetUnit(ps.TimeResponse)
+	s.channels = append(s.channels, rt)
+	return s
+}
+
+// pushSensor is a HTTP Push Data Advanced sensor receiving an object's summary
+func pushSensor(name, token, tags string) Check {
+	return Check{
+		ID:       name,
+		Kind:     "httppushdataadvanced",
+		Requires: "ping",
+		Createdata: Createdata{Name: name, Tags: tags, Priority: "3", Errorintervalsdown: "5",
+			Autoacknowledge: "0", Token: token,
+		},
+	}
+}
+
+// PushTemplate creates a template of push sensors for the selected objects and adds their tokens to t
+func (c *Client) PushTemplate(ctx context.Context, sel Selection, Age time.Duration, tplate, profile string, t *PushTokens) error {
+	tags := strings.Join(sel.Tags, ",")
+	d := NewDeviceTemplate(Age, tags, profile)
+
+	ctx, cancel := c.inventoryCtx(ctx)
+	defer cancel()
+	tm := NewTagMap()
+	err := c.discover(ctx, sel, tm)
+	if err != nil {
+		return PhaseError(ctx, "inventory", err)
+	}
+	moidNames, err := newMoidNames(ctx, c)
+	if err != nil {
+		return PhaseError(ctx, "inventory", err)
+	}
+	meta, err := c.obMeta(tm, moidNames, Age, profile)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range meta.Items {
+		token, err := t.token(v.ID, moidNames.Gettype(v.ID), moidNames.GetName(v.ID))
+		if err != nil {
+			return err
+		}
+		err = d.add(pushSensor(v.Name, token, tags))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = d.save(tplate)
+	if err != nil {
+		return fmt.Errorf("failed to save file %v", err)
+	}
+	return nil
+}
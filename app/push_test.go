@@ -0,0 +1,251 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	ps "github.com/PRTG/go-prtg-sensor-api"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// prtgReceiver stands in for the probe push endpoint, it accepts the tokens it is given
+type prtgReceiver struct {
+	mu     sync.Mutex
+	tokens map[string]bool
+	got    map[string]ps.SensorResponse
+}
+
+func (p *prtgReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, "/")
+	if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	resp := ps.SensorResponse{}
+	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	matching := "0"
+	if p.tokens[token] {
+		matching = "1"
+		p.got[token] = resp
+	}
+	_, _ = w.Write([]byte(`{"status":"Ok","Matching Sensors":"` + matching + `"}`))
+}
+
+func TestPushTokens(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prtgvmware")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	fn := filepath.Join(dir, "tokens.yml")
+	tk, err := LoadPushTokens(fn)
+	if err != nil {
+		t.Fatalf("missing file %v", err)
+	}
+	a, _ := tk.token("vm-1", "VirtualMachine", "web1")
+	b, _ := tk.token("host-1", "HostSystem", "esx1")
+	if len(a) != 32 || a == b {
+		t.Fatalf("tokens %v %v", a, b)
+	}
+	tk.URL = "http://probe:5050"
+	if err := tk.Save(fn); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(fn); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("token file mode %v %v", fi.Mode(), err)
+	}
+
+	tk, err = LoadPushTokens(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := tk.token("vm-1", "VirtualMachine", "web1 renamed"); again != a {
+		t.Errorf("token changed %v %v", a, again)
+	}
+	if tk.Objects["vm-1"].Name != "web1 renamed" || tk.URL != "http://probe:5050" {
+		t.Errorf("loaded %+v", tk)
+	}
+	sel := tk.Selection()
+	if len(sel.Refs) != 2 || sel.Refs[0].Value != "host-1" || sel.Refs[0].Type != "HostSystem" || sel.Empty() {
+		t.Errorf("selection %+v", sel)
+	}
+
+	_ = ioutil.WriteFile(fn, []byte("objects: [\n"), 0600)
+	if _, err := LoadPushTokens(fn); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestPusher(t *testing.T) {
+	rx := &prtgReceiver{tokens: map[string]bool{"AAA": true}, got: make(map[string]ps.SensorResponse)}
+	srv := httptest.NewServer(rx)
+	defer srv.Close()
+
+	tk := &PushTokens{URL: srv.URL + "/", Objects: map[string]PushToken{
+		"vm-1":  {Token: "AAA", Type: "VirtualMachine", Name: "web1"},
+		"vm-2":  {Token: "BBB", Type: "VirtualMachine", Name: "web2"},
+		"vm-3":  {Token: "AAA"},
+		"vm-4":  {},
+		"bad-1": {Token: "AAA"},
+	}}
+	p := &Pusher{Tokens: tk}
+	ok := Result{Type: "VirtualMachine", Moid: "vm-1", Name: "web1", Text: "all good", Duration: 2 * time.Second, Samples: []Sample{
+		{SensorChannel: ps.SensorChannel{Channel: "cpu", Value: "12"}},
+	}}
+
+	tests := []struct {
+		name    string
+		r       Result
+		wantErr string
+	}{
+		{"delivered", ok, ""},
+		{"unknown token", Result{Moid: "vm-2", Name: "web2"}, "no sensor has token BBB"},
+		{"no token", Result{Moid: "vm-5", Type: "VirtualMachine"}, "no push token for VirtualMachine vm-5"},
+		{"empty token", Result{Moid: "vm-4"}, "no push token"},
+		{"failed summary", Result{Moid: "vm-3", Err: "timed out"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.Push(context.Background(), tt.r)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Push() error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// vm-3 reused the token of vm-1 and reported an error
+	got := rx.got["AAA"].SensorResults
+	if got.Error != "1" || got.Text != "timed out" {
+		t.Errorf("error result %+v", got)
+	}
+
+	err := (&Pusher{Tokens: &PushTokens{URL: srv.URL + "/wrong/path", Objects: tk.Objects}}).Push(context.Background(), ok)
+	if err == nil {
+		t.Error("expected an unmatched push to fail")
+	}
+	err = (&Pusher{Tokens: &PushTokens{Objects: tk.Objects}}).Push(context.Background(), ok)
+	if err == nil || !strings.Contains(err.Error(), "no push url") {
+		t.Errorf("missing url %v", err)
+	}
+}
+
+func TestPusherLimit(t *testing.T) {
+	rx := &prtgReceiver{tokens: map[string]bool{"AAA": true}, got: make(map[string]ps.SensorResponse)}
+	srv := httptest.NewServer(rx)
+	defer srv.Close()
+	tk := &PushTokens{URL: srv.URL + "/", Objects: map[string]PushToken{"vds-1": {Token: "AAA"}}}
+
+	r := Result{Type: "VmwareDistributedVirtualSwitch", Moid: "vds-1", Name: "vds", Text: "ok"}
+	for i := 0; i < 60; i++ {
+		r.Samples = append(r.Samples, Sample{
+			SensorChannel: ps.SensorChannel{Channel: fmt.Sprintf("pg%02d ports", i), Value: strconv.Itoa(i)},
+			Family:        "ports", Instance: fmt.Sprintf("pg%02d", i),
+		})
+	}
+
+	tests := []struct {
+		name     string
+		limit    ChannelLimit
+		channels int
+		text     string
+		err      bool
+	}{
+		{"first page", ChannelLimit{}, maxChannels, "page 1 of 2", false},
+		{"second page", ChannelLimit{Page: 2}, 12, "page 2 of 2", false},
+		{"missing page", ChannelLimit{Page: 3}, 0, "page 3 not found", true},
+		{"aggregate", ChannelLimit{Aggregate: true}, 5, "worst ports pg59", false},
+		{"top", ChannelLimit{Top: 5}, 6, "worst 5 of 60", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Pusher{Tokens: tk, Limit: tt.limit}).Push(context.Background(), r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := rx.got["AAA"].SensorResults
+			if len(got.SensorChannels) != tt.channels || !strings.Contains(got.Text, tt.text) || (got.Error == "1") != tt.err {
+				t.Errorf("Push() sent %v channels, text %q, error %q", len(got.SensorChannels), got.Text, got.Error)
+			}
+		})
+	}
+}
+
+func TestPushTemplate(t *testing.T) {
+	c, stop := newSimClient(t, nil)
+	defer stop()
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "prtgvmware")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	tk := &PushTokens{Objects: make(map[string]PushToken)}
+	err = c.PushTemplate(ctx, Selection{Folders: []string{"/DC0/host"}}, time.Hour, filepath.Join(dir, "push"), "", tk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt, ok := tk.Objects["host-21"]
+	if !ok || pt.Type != "HostSystem" || pt.Name != "DC0_H0" {
+		t.Fatalf("tokens %+v", tk.Objects)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "push.odt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `kind="httppushdataadvanced"`) || !strings.Contains(string(b), "<httppushtoken>"+pt.Token+"</httppushtoken>") {
+		t.Errorf("template missing push sensor for host-21\n%s", b)
+	}
+
+	rx := &prtgReceiver{tokens: map[string]bool{pt.Token: true}, got: make(map[string]ps.SensorResponse)}
+	srv := httptest.NewServer(rx)
+	defer srv.Close()
+	tk.URL = srv.URL
+	p := &Pusher{Tokens: tk}
+	err = c.Collect(ctx, tk.Selection(), CollectOptions{SnapAge: time.Hour}, func(r Result) error {
+		if r.Moid != "host-21" {
+			return nil
+		}
+		return p.Push(ctx, r)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := rx.got[pt.Token].SensorResults
+	if got.Error != "0" || len(got.SensorChannels) == 0 {
+		t.Errorf("host-21 push %+v", got)
+	}
+	if last := got.SensorChannels[len(got.SensorChannels)-1]; last.Channel != "Execution time" {
+		t.Errorf("last channel %v", last.Channel)
+	}
+}
@@ -0,0 +1,276 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/vmware/govmomi/vim25/soap"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Exchange is one recorded round trip with vcenter
+type Exchange struct {
+	Seq    int    `json:"seq"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Op is the SOAP method called, empty for REST requests
+	Op string `json:"op,omitempty"`
+	// Hash identifies the redacted request body
+	Hash        string `json:"hash"`
+	Request     string `json:"request,omitempty"`
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType,omitempty"`
+	Response    string `json:"response"`
+}
+
+var (
+	// soapSecrets are request elements that hold credentials or session ids
+	soapSecrets = regexp.MustCompile(`(<(?:\w+:)?(?:userName|password|sessionID|token)(?:\s[^>]*)?>)[^<]*(</)`)
+	soapOp      = regexp.MustCompile(`<(?:\w+:)?Body[^>]*>\s*<(?:\w+:)?(\w+)`)
+)
+
+// redact removes credentials from a request body
+func redact(body []byte) []byte {
+	return soapSecrets.ReplaceAll(body, []byte("${1}redacted${2}"))
+}
+
+// newExchange describes a request, the body is redacted before it is hashed
+func newExchange(method, path string, body []byte) Exchange {
+	body = redact(body)
+	sum := sha256.Sum256(body)
+	e := Exchange{Method: method, Path: path, Hash: hex.EncodeToString(sum[:]), Request: string(body)}
+	if m := soapOp.FindSubmatch(body); m != nil {
+		e.Op = string(m[1])
+	}
+	return e
+}
+
+// Recorder saves every round trip with vcenter to a folder, credentials, cookies and session ids are left out
+type Recorder struct {
+	dir string
+	mu  sync.Mutex
+	seq int
+}
+
+// recorder is set by SetRecorder and captures the round trips of every client created afterwards
+var recorder *Recorder
+
+// NewRecorder creates dir, a folder already holding a recording is refused so captures never mix
+func NewRecorder(dir string) (*Recorder, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, fmt.Errorf("record %v", err)
+	}
+	old, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(old) > 0 {
+		return nil, fmt.Errorf("record %v already holds a recording", dir)
+	}
+	return &Recorder{dir: dir}, nil
+}
+
+// SetRecorder records the traffic of clients created after the call, nil stops recording
+func SetRecorder(r *Recorder) {
+	recorder = r
+}
+
+// recordTransport routes the round trips of sc through the recorder when one is set
+func recordTransport(sc *soap.Client) {
+	if recorder == nil || sc == nil {
+		return
+	}
+	rt := sc.Client.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	sc.Client.Transport = &recordingTransport{next: rt, r: recorder}
+}
+
+type recordingTransport struct {
+	next http.RoundTripper
+	r    *Recorder
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	rb, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(rb))
+
+	e := newExchange(req.Method, req.URL.RequestURI(), body)
+	e.Status = resp.StatusCode
+	e.ContentType = resp.Header.Get("Content-Type")
+	e.Response = string(rb)
+	// the rest login answers with the session id
+	if req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/cis/session") && req.URL.RawQuery == "" {
+		e.Response = `{"value":"redacted"}`
+	}
+	return resp, t.r.save(e)
+}
+
+func (r *Recorder) save(e Exchange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	e.Seq = r.seq
+	b := &bytes.Buffer{}
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(e)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(r.dir, fmt.Sprintf("%05d.json", e.Seq)), b.Bytes(), 0600)
+	if err != nil {
+		return fmt.Errorf("record %v", err)
+	}
+	return nil
+}
+
+// Replay answers requests from a recording, identical requests get their responses in recorded order and
+// requests that changed, I.E performance queries covering a newer time range, get the next response of the same method
+type Replay struct {
+	mu        sync.Mutex
+	exchanges []Exchange
+	used      []bool
+	srv       *http.Server
+}
+
+// LoadReplay reads a recording made with NewRecorder
+func LoadReplay(dir string) (*Replay, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("replay %v holds no recording", dir)
+	}
+	r := &Replay{}
+	for _, fn := range files {
+		b, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return nil, fmt.Errorf("replay %v", err)
+		}
+		e := Exchange{}
+		err = json.Unmarshal(b, &e)
+		if err != nil {
+			return nil, fmt.Errorf("replay %v %v", fn, err)
+		}
+		r.exchanges = append(r.exchanges, e)
+	}
+	sort.SliceStable(r.exchanges, func(i, j int) bool { return r.exchanges[i].Seq < r.exchanges[j].Seq })
+	r.used = make([]bool, len(r.exchanges))
+	return r, nil
+}
+
+// match returns the recorded exchange answering e
+func (r *Replay) match(e Exchange) (Exchange, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	same := []func(Exchange) bool{
+		func(x Exchange) bool { return x.Method == e.Method && x.Path == e.Path && x.Hash == e.Hash },
+		func(x Exchange) bool { return x.Method == e.Method && x.Path == e.Path && e.Op != "" && x.Op == e.Op },
+	}
+	for _, f := range same {
+		last := -1
+		for i, x := range r.exchanges {
+			if !f(x) {
+				continue
+			}
+			if !r.used[i] {
+				r.used[i] = true
+				return x, true
+			}
+			last = i
+		}
+		// requests made more often than during recording get the latest answer again
+		if last >= 0 {
+			return r.exchanges[last], true
+		}
+	}
+	return Exchange{}, false
+}
+
+func (r *Replay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	e := newExchange(req.Method, req.URL.RequestURI(), body)
+	x, ok := r.match(e)
+	if !ok {
+		http.Error(w, fmt.Sprintf("no recorded response for %v %v %v", e.Method, e.Path, e.Op), http.StatusNotFound)
+		return
+	}
+	if x.ContentType != "" {
+		w.Header().Set("Content-Type", x.ContentType)
+	}
+	w.WriteHeader(x.Status)
+	_, _ = w.Write([]byte(x.Response))
+}
+
+// Start serves the recording on a local port and returns the sdk url to use in place of vcenter
+func (r *Replay) Start() (*url.URL, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("replay %v", err)
+	}
+	r.srv = &http.Server{Handler: r}
+	go func() { _ = r.srv.Serve(ln) }()
+	return &url.URL{Scheme: "http", Host: ln.Addr().String(), Path: "/sdk"}, nil
+}
+
+// Close stops serving the recording
+func (r *Replay) Close() error {
+	if r.srv == nil {
+		return nil
+	}
+	return r.srv.Close()
+}
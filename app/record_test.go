@@ -0,0 +1,142 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"login", `<Login><userName>admin</userName><password>s3cret</password></Login>`, `<Login><userName>redacted</userName><password>redacted</password></Login>`},
+		{"prefixed", `<v:password xsi:type="string">s3cret</v:password>`, `<v:password xsi:type="string">redacted</v:password>`},
+		{"other", `<name>password</name>`, `<name>password</name>`},
+	}
+	for _, tt := range tests {
+		if got := string(redact([]byte(tt.in))); got != tt.want {
+			t.Errorf("%v got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	e := newExchange("POST", "/sdk", []byte(`<Envelope><Body><QueryPerf xmlns="urn:vim25"></QueryPerf></Body></Envelope>`))
+	if e.Op != "QueryPerf" {
+		t.Errorf("op %q", e.Op)
+	}
+}
+
+func TestReplayMatch(t *testing.T) {
+	a := newExchange("POST", "/sdk", []byte(`<Body><QueryPerf>1</QueryPerf></Body>`))
+	b := newExchange("POST", "/sdk", []byte(`<Body><QueryPerf>2</QueryPerf></Body>`))
+	a.Response, b.Response = "a", "b"
+	r := &Replay{exchanges: []Exchange{a, b}, used: make([]bool, 2)}
+
+	// repeated requests get the same answer again, changed requests get the next unused answer of the same method
+	for _, tt := range []struct{ body, want string }{{"2", "b"}, {"2", "b"}, {"3", "a"}} {
+		x, ok := r.match(newExchange("POST", "/sdk", []byte(`<Body><QueryPerf>`+tt.body+`</QueryPerf></Body>`)))
+		if !ok || x.Response != tt.want {
+			t.Errorf("request %v got %v %v, want %v", tt.body, x.Response, ok, tt.want)
+		}
+	}
+	if _, ok := r.match(newExchange("POST", "/sdk", []byte(`<Body><Logout/></Body>`))); ok {
+		t.Error("unrecorded method matched")
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	s, stop := newSim(t, simulator.VPX(), nil)
+	defer stop()
+
+	dir, err := ioutil.TempDir("", "prtgvmware")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	rec, err := NewRecorder(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sel := Selection{Refs: []types.ManagedObjectReference{{Type: "HostSystem", Value: "host-21"}, {Type: "VirtualMachine", Value: "vm-54"}}}
+	run := func(ctx context.Context, c Client) map[string]Result {
+		got := make(map[string]Result)
+		err := c.Collect(ctx, sel, CollectOptions{SnapAge: time.Hour, Workers: 1}, func(r Result) error {
+			r.Duration, r.Time = 0, time.Time{}
+			got[r.Moid] = r
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	ctx := context.Background()
+	SetRecorder(rec)
+	c, err := NewClient(ctx, s.URL, "user", "pass", false, TLSOptions{Insecure: true})
+	SetRecorder(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := run(ctx, c)
+	_ = c.Logout()
+	if len(want) != 2 || want["vm-54"].Err != "" {
+		t.Fatalf("recorded %+v", want)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		b, err := ioutil.ReadFile(dir + "/" + f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(b), "<password>pass<") {
+			t.Errorf("%v holds the password", f.Name())
+		}
+	}
+	if _, err := NewRecorder(dir); err == nil {
+		t.Error("recording into a used folder")
+	}
+
+	r, err := LoadReplay(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := r.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = r.Close() }()
+	rc, err := NewClient(ctx, u, "replay", "replay", false, TLSOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := run(ctx, rc); !reflect.DeepEqual(got, want) {
+		t.Errorf("replay\n%+v\nwant\n%+v", got, want)
+	}
+}
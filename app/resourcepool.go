@@ -0,0 +1,111 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"fmt"
+	ps "github.com/PRTG/go-prtg-sensor-api"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"time"
+)
+
+// rpSummaryDefault are the resource pool counters read, pools only keep historical stats
+var rpSummaryDefault = []string{
+	"cpu.usagemhz.average", "cpu.cpuentitlement.latest",
+	"mem.consumed.average", "mem.active.average", "mem.mementitlement.latest",
+	"mem.ballooned.average", "mem.swapped.average",
+}
+
+// ResourcePoolSummary stats for a resource pool
+func (c *Client) ResourcePoolSummary(ctx context.Context, name, moid string, js bool) (err error) {
+	start := time.Now()
+	ictx, cancel := c.inventoryCtx(ctx)
+	defer cancel()
+
+	id := types.ManagedObjectReference{
+		Type:  "ResourcePool",
+		Value: moid,
+	}
+	if moid == "" {
+		id, err = c.findOne(ictx, name, id.Type)
+		if err != nil {
+			return PhaseError(ictx, "inventory", err)
+		}
+	}
+	rp := mo.ResourcePool{}
+	err = c.retrieveOne(ictx, id, []string{"name", "config", "runtime", "vm", "resourcePool", "triggeredAlarmState"}, &rp)
+	if err != nil {
+		return PhaseError(ictx, "inventory", errCheck(name, id, fmt.Errorf("resource pool v.properties %v", err)))
+	}
+
+	pr := c.prtgData(id, rp.Name)
+	_ = pr.add(managedEntityStatus(rp.Runtime.OverallStatus), ps.SensorChannel{Channel: "Overall Status", Unit: "Custom", CustomUnit: "Custom", ValueLookup: lookupStatus})
+	_ = pr.add(triggeredAlarms(rp.TriggeredAlarmState), ps.SensorChannel{Channel: "Triggered Alarms", Unit: "Count", LimitMaxWarning: "1", LimitWarningMsg: "triggered alarms present"})
+	_ = pr.add(len(rp.Vm), ps.SensorChannel{Channel: "VMs", Unit: "Count"})
+	_ = pr.add(len(rp.ResourcePool), ps.SensorChannel{Channel: "Child Pools", Unit: "Count"})
+
+	// cpu is in MHz throughout, memory allocations are in MB and runtime memory in bytes
+	poolAllocation(pr, "CPU", rp.Config.CpuAllocation, ps.SensorChannel{Unit: "Custom", VolumeSize: "One", CustomUnit: "MHz"}, 1)
+	poolAllocation(pr, "Memory", rp.Config.MemoryAllocation, ps.SensorChannel{Unit: "BytesMemory"}, 1024*1024)
+	poolUsage(pr, "CPU", rp.Runtime.Cpu, ps.SensorChannel{Unit: "Custom", VolumeSize: "One", CustomUnit: "MHz"})
+	poolUsage(pr, "Memory", rp.Runtime.Memory, ps.SensorChannel{Unit: "BytesMemory"})
+	elapsed := time.Since(start)
+
+	err = c.Metrics(ctx, id, pr, rpSummaryDefault, historicalPerfInterval)
+	if err != nil {
+		return err
+	}
+	_ = pr.print(elapsed, js)
+	return nil
+}
+
+// poolAllocation adds the configured reservation, limit and shares, limits are only reported when set
+func poolAllocation(pr *prtgData, kind string, a types.ResourceAllocationInfo, unit ps.SensorChannel, scale int64) {
+	ch := func(name string) ps.SensorChannel {
+		c := unit
+		c.Channel = kind + " " + name
+		return c
+	}
+	if a.Reservation != nil {
+		_ = pr.add(*a.Reservation*scale, ch("Reservation"))
+	}
+	if a.Limit != nil && *a.Limit >= 0 {
+		_ = pr.add(*a.Limit*scale, ch("Limit"))
+	}
+	if a.Shares != nil {
+		_ = pr.add(a.Shares.Shares, ps.SensorChannel{Channel: kind + " Shares", Unit: "Count"})
+	}
+}
+
+// poolUsage adds what the pool uses against the most it may use, the limit or what its parent can give it
+func poolUsage(pr *prtgData, kind string, u types.ResourcePoolResourceUsage, unit ps.SensorChannel) {
+	ch := func(name string) ps.SensorChannel {
+		c := unit
+		c.Channel = kind + " " + name
+		return c
+	}
+	_ = pr.add(u.OverallUsage, ch("Usage"))
+	_ = pr.add(u.MaxUsage, ch("Max Usage"))
+	_ = pr.add(u.ReservationUsed, ch("Reservation Used"))
+	_ = pr.add(u.UnreservedForVm, ch("Unreserved"))
+	if u.MaxUsage > 0 {
+		_ = pr.add(u.OverallUsage*100/u.MaxUsage, ps.SensorChannel{Channel: kind + " Usage (Percent of Max)", Unit: "Percent",
+			LimitMaxWarning: "80", LimitMaxError: "95", LimitWarningMsg: "pool close to its limit", LimitErrorMsg: "pool at its limit", LimitMode: "1"})
+	}
+}
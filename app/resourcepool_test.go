@@ -0,0 +1,93 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"testing"
+	"time"
+)
+
+func TestResourcePoolSummary(t *testing.T) {
+	c, stop := newSimClient(t, nil)
+	defer stop()
+	ctx := context.Background()
+
+	// a tenant pool with a cpu limit and no memory limit
+	root := object.NewResourcePool(c.c, simulator.Map.Any("ResourcePool").Reference())
+	spec := types.DefaultResourceConfigSpec()
+	limit := int64(2000)
+	spec.CpuAllocation.Limit = &limit
+	tenant, err := root.Create(ctx, "tenant", spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got Result
+	c.SetSink(func(r Result) error {
+		got = r
+		return nil
+	})
+	err = c.ResourcePoolSummary(ctx, "", tenant.Reference().Value, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]string)
+	for _, s := range got.Samples {
+		values[s.Channel] = s.Value
+	}
+	for ch, v := range map[string]string{"VMs": "0", "Child Pools": "0", "CPU Limit": "2000", "Overall Status": "0"} {
+		if values[ch] != v {
+			t.Errorf("%v = %q, want %v", ch, values[ch], v)
+		}
+	}
+	if _, ok := values["Memory Limit"]; ok {
+		t.Error("unlimited memory reported as a limit")
+	}
+	if _, ok := values["Memory Usage (Percent of Max)"]; !ok {
+		t.Errorf("no memory usage in %v", values)
+	}
+
+	// a pool brings its vms and child pools along when selected
+	ids, err := c.getChildIds(ctx, root.Reference())
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := make(map[string]int)
+	for _, id := range ids {
+		kinds[id.Type]++
+	}
+	if kinds["ResourcePool"] != 2 || kinds["VirtualMachine"] == 0 {
+		t.Errorf("children %v", kinds)
+	}
+	tm := NewTagMap()
+	tm.add(tenant.Reference(), "tenants")
+	names, err := newMoidNames(ctx, &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := c.obMeta(tm, names, time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(meta.Items) != 1 || meta.Items[0].Name != "Pool tenant" || meta.Items[0].Params != "rpSummary --oid "+tenant.Reference().Value {
+		t.Errorf("items %+v", meta.Items)
+	}
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	ps "github.com/PRTG/go-prtg-sensor-api"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Result is a summary as structured data for writers other than PRTG sensors
+type Result struct {
+	// Type is the vSphere type, I.E VirtualMachine, snapshots for the snapshot sensor, hardware for host hardware health
+	// or compliance for host baseline checks
+	Type string
+	Moid string
+	Name string
+	// Tags are the tags, name patterns or folders that selected the object during collection
+	Tags     []string
+	Text     string
+	Err      string
+	Duration time.Duration
+	Time     time.Time
+	Samples  []Sample
+}
+
+// Sample is a channel with the labels needed to group it with the same value of other objects
+type Sample struct {
+	ps.SensorChannel
+	// Family names what is measured, I.E cpu.usage.average, channels without one use their channel name
+	Family string
+	// Instance separates values of one family on the same object, I.E a guest disk path
+	Instance string
+}
+
+// Float returns the channel value as a number
+func (s Sample) Float() (float64, bool) {
+	v, err := strconv.ParseFloat(s.Value, 64)
+	return v, err == nil
+}
+
+// Key is the family, or the channel name for channels without one
+func (s Sample) Key() string {
+	if s.Family != "" {
+		return s.Family
+	}
+	return s.Channel
+}
+
+// SetSink sends summary results to f instead of writing PRTG output
+func (c *Client) SetSink(f func(Result) error) {
+	c.sink = f
+}
+
+// sampleMeta holds the family and instance of a channel added with addSample
+type sampleMeta struct {
+	family, instance string
+}
+
+// result builds the structured form of p
+func (p *prtgData) result(checkTime time.Duration) Result {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	r := Result{
+		Type:     p.kind,
+		Moid:     p.moid,
+		Name:     p.name,
+		Text:     p.messageText(),
+		Err:      p.err,
+		Duration: checkTime,
+		Time:     time.Now(),
+		Samples:  make([]Sample, 0, len(p.items)),
+	}
+	for _, item := range p.items {
+		m := p.meta[item.Channel]
+		r.Samples = append(r.Samples, Sample{SensorChannel: item, Family: m.family, Instance: m.instance})
+	}
+	return r
+}
+
+// shortType is the object type used in metric names
+func shortType(t string) string {
+	switch t {
+	case "VirtualMachine":
+		return "vm"
+	case "HostSystem":
+		return "host"
+	case "VmwareDistributedVirtualSwitch":
+		return "vds"
+	case "ClusterComputeResource":
+		return "cluster"
+	case "ResourcePool":
+		return "pool"
+	case "StoragePod":
+		return "pod"
+	case "DistributedVirtualPortgroup":
+		return "pg"
+	}
+	return strings.ToLower(t)
+}
+
+// metricName reduces s to lower case letters, digits and single underscores
+func metricName(s string) string {
+	b := strings.Builder{}
+	under := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			under = false
+			continue
+		}
+		if !under && b.Len() > 0 {
+			b.WriteByte('_')
+			under = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
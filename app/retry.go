@@ -0,0 +1,207 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	"io"
+	"math/rand"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// attempts made for transient faults, including the first
+	retryAttempts = 3
+	// first backoff, doubled on each attempt and jittered by ±50%
+	retryBackoff = 250 * time.Millisecond
+)
+
+// credentials are kept so an expired session can be replaced mid sensor, they are shared by
+// every copy of a client so copies running in parallel log in again only once
+type credentials struct {
+	u     url.URL
+	user  string
+	pw    string
+	cache bool
+	tls   TLSOptions
+	mu    sync.Mutex
+	// last is the newest login, copies still on an older connection take it instead of logging in
+	last Client
+}
+
+// retry runs f until it succeeds, logging in again once on authentication faults
+// and backing off between attempts on transient faults. f must read the connection
+// from c on every call as logging in replaces it
+func (c *Client) retry(ctx context.Context, f func() error) (err error) {
+	relogged := false
+	for attempt := 1; ; attempt++ {
+		err = f()
+		switch {
+		case err == nil:
+			return nil
+		case ctx.Err() != nil:
+			return err
+		case isAuthFault(err) && !relogged:
+			relogged = true
+			if lerr := c.relogin(ctx); lerr != nil {
+				return fmt.Errorf("%v, logging in again failed %v", err, lerr)
+			}
+			continue
+		case isTransient(err) && attempt < retryAttempts:
+		default:
+			return err
+		}
+
+		select {
+		case <-time.After(backoff(attempt)):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// backoff returns the jittered wait before the next attempt
+func backoff(attempt int) time.Duration {
+	d := retryBackoff << uint(attempt-1)
+	return d/2 + time.Duration(rand.Int63n(int64(d)))
+}
+
+// relogin discards the cached session and replaces the connection with a new login, a copy of
+// the client takes the login of another copy or a pooled request when they already logged in again
+func (c *Client) relogin(ctx context.Context) error {
+	if c.creds == nil {
+		return fmt.Errorf("no credentials to log in with")
+	}
+	pc := c.pooled
+	if pc != nil {
+		pc.mu.Lock()
+		defer pc.mu.Unlock()
+		if pc.c.c != c.c {
+			c.replace(pc.shared())
+			return nil
+		}
+	}
+
+	cr := c.creds
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if pc == nil && cr.last.c != nil && cr.last.c != c.c {
+		c.replace(cr.last)
+		return nil
+	}
+	u := cr.u
+	if cr.cache {
+		_ = newSessionFile(&u, cr.user).remove()
+		removeLegacySession(u.Host)
+	}
+	nc, err := NewClient(ctx, &u, cr.user, cr.pw, cr.cache, cr.tls)
+	if err != nil {
+		return err
+	}
+	nc.creds = cr
+	cr.last = nc
+	if pc != nil {
+		pc.c = nc
+		pc.checked = time.Now()
+		nc = pc.shared()
+	}
+	c.replace(nc)
+	return nil
+}
+
+// replace swaps in the connection from nc, keeping the output and budgets of c
+func (c *Client) replace(nc Client) {
+	c.c, c.r, c.m, c.cache, c.creds = nc.c, nc.r, nc.m, nc.cache, nc.creds
+	// a shared client stays marked cached so the caller doesn't log the pool out
+	c.Cached = c.Cached || nc.Cached
+}
+
+// retrieveOne loads properties of a single object with retries
+func (c *Client) retrieveOne(ctx context.Context, ref types.ManagedObjectReference, ps []string, dst interface{}) error {
+	return c.retry(ctx, func() error {
+		return property.DefaultCollector(c.c).RetrieveOne(ctx, ref, ps, dst)
+	})
+}
+
+// retrieve loads properties of several objects with retries, dst is a pointer to a slice
+func (c *Client) retrieve(ctx context.Context, refs []types.ManagedObjectReference, ps []string, dst interface{}) error {
+	if len(refs) == 0 {
+		return nil
+	}
+	return c.retry(ctx, func() error {
+		return property.DefaultCollector(c.c).Retrieve(ctx, refs, ps, dst)
+	})
+}
+
+// faultOf returns the vim fault carried by err, if any
+func faultOf(err error) interface{} {
+	switch {
+	case soap.IsSoapFault(err):
+		return soap.ToSoapFault(err).VimFault()
+	case soap.IsVimFault(err):
+		return soap.ToVimFault(err)
+	}
+	return nil
+}
+
+// isAuthFault is true when the server no longer accepts the session
+func isAuthFault(err error) bool {
+	switch faultOf(err).(type) {
+	case types.NotAuthenticated, *types.NotAuthenticated:
+		return true
+	}
+	e := err.Error()
+	return strings.Contains(e, "401 Unauthorized") || strings.Contains(e, "NotAuthenticated") ||
+		strings.Contains(e, "session is not authenticated")
+}
+
+// isTransient is true for faults that are likely to succeed on a second attempt
+func isTransient(err error) bool {
+	switch faultOf(err).(type) {
+	case types.HostCommunication, *types.HostCommunication,
+		types.RequestCanceled, *types.RequestCanceled:
+		return true
+	}
+	if soap.IsRegularError(err) {
+		err = soap.ToRegularError(err)
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	e := err.Error()
+	for _, s := range []string{"502 Bad Gateway", "503 Service Unavailable", "504 Gateway Timeout"} {
+		if strings.Contains(e, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/vmware/govmomi/session"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+	"io"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func soapFault(f types.AnyType) error {
+	sf := &soap.Fault{Code: "ServerFaultCode", String: "fault"}
+	sf.Detail.Fault = f
+	return soap.WrapSoapFault(sf)
+}
+
+func TestFaultClass(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		auth      bool
+		transient bool
+	}{
+		{"not authenticated", soapFault(types.NotAuthenticated{}), true, false},
+		{"not authenticated vim fault", soap.WrapVimFault(&types.NotAuthenticated{}), true, false},
+		{"rest 401", fmt.Errorf("GET https://vc/rest: 401 Unauthorized"), true, false},
+		{"host communication", soapFault(types.HostCommunication{}), false, true},
+		{"eof", soap.WrapRegularError(io.EOF), false, true},
+		{"connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), false, true},
+		{"service unavailable", fmt.Errorf("503 Service Unavailable"), false, true},
+		{"invalid argument", soapFault(types.InvalidArgument{}), false, false},
+		{"plain", fmt.Errorf("object not found"), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isAuthFault(tt.err); got != tt.auth {
+				t.Errorf("isAuthFault() = %v, want %v", got, tt.auth)
+			}
+			if got := isTransient(tt.err); got != tt.transient {
+				t.Errorf("isTransient() = %v, want %v", got, tt.transient)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 1; attempt <= retryAttempts; attempt++ {
+		d := retryBackoff << uint(attempt-1)
+		for i := 0; i < 20; i++ {
+			if got := backoff(attempt); got < d/2 || got >= d+d/2 {
+				t.Errorf("backoff(%v) = %v, want within %v and %v", attempt, got, d/2, d+d/2)
+			}
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	expired, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-expired.Done()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		errs    []error
+		calls   int
+		wantErr bool
+	}{
+		{"success", context.Background(), nil, 1, false},
+		{"transient then success", context.Background(), []error{io.EOF}, 2, false},
+		{"transient exhausted", context.Background(), []error{io.EOF, io.EOF, io.EOF, io.EOF}, retryAttempts, true},
+		{"permanent", context.Background(), []error{fmt.Errorf("object not found")}, 1, true},
+		{"auth without credentials", context.Background(), []error{soapFault(types.NotAuthenticated{})}, 1, true},
+		{"expired context", expired, []error{io.EOF}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{}
+			calls := 0
+			err := c.retry(tt.ctx, func() error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("retry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.calls {
+				t.Errorf("retry() made %v calls, want %v", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestRelogin(t *testing.T) {
+	s, stop := newSim(t, simulator.ESX(), nil)
+	defer stop()
+
+	ctx := context.Background()
+	c, err := NewClient(ctx, s.URL, "user", "pass", false, TLSOptions{Insecure: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.out = &bytes.Buffer{}
+
+	// expire the session behind the client's back
+	if err = session.NewManager(c.c).Logout(ctx); err != nil {
+		t.Fatal(err)
+	}
+	old := c.c
+	if err = c.HostSummary(ctx, "", "ha-host", false); err != nil {
+		t.Fatalf("HostSummary() after session expired %v", err)
+	}
+	if c.c == old {
+		t.Error("connection was not replaced")
+	}
+
+	// copies of a client, as used by collect workers, share one new login
+	if err = session.NewManager(c.c).Logout(ctx); err != nil {
+		t.Fatal(err)
+	}
+	copies := make([]Client, 4)
+	wg := sync.WaitGroup{}
+	for i := range copies {
+		copies[i] = c
+		copies[i].out = &bytes.Buffer{}
+		wg.Add(1)
+		go func(wc *Client) {
+			defer wg.Done()
+			if err := wc.HostSummary(ctx, "", "ha-host", false); err != nil {
+				t.Errorf("copy HostSummary() after session expired %v", err)
+			}
+		}(&copies[i])
+	}
+	wg.Wait()
+	for _, wc := range copies[1:] {
+		if wc.c == c.c || wc.c != copies[0].c {
+			t.Errorf("copies logged in separately %p %p", wc.c, copies[0].c)
+		}
+	}
+
+	// a pooled client shares the new login with the pool
+	p := NewPool(false)
+	defer p.Close()
+	u, _ := soap.ParseURL(s.URL.String())
+	pc, err := p.Get(ctx, u, "user", "pass", TLSOptions{Insecure: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pc.out = &bytes.Buffer{}
+	if err = session.NewManager(pc.c).Logout(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err = pc.HostSummary(ctx, "", "ha-host", false); err != nil {
+		t.Fatalf("pooled HostSummary() after session expired %v", err)
+	}
+	again, err := p.Get(ctx, u, "user", "pass", TLSOptions{Insecure: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.c != pc.c {
+		t.Error("pool kept the expired session")
+	}
+	if !pc.Cached {
+		t.Error("pooled client lost its cached mark")
+	}
+}
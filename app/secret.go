@@ -0,0 +1,122 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// PasswordEnv is read for the password when no other source is given
+const PasswordEnv = "PRTGVMWARE_PASSWORD"
+
+// environment values PRTG sets for EXE sensors with "Set placeholders as environment values" enabled
+const (
+	prtgHostEnv     = "prtg_host"
+	prtgUserEnv     = "prtg_windowsuser"
+	prtgPasswordEnv = "prtg_windowspassword"
+)
+
+// Login holds the connection details a sensor needs, empty fields are filled from other sources
+type Login struct {
+	URL      string
+	Username string
+	Password string
+	// PasswordFile holds the password on its first line
+	PasswordFile string
+	// PasswordCommand prints the password on stdout, it is run by the system shell
+	PasswordCommand string
+}
+
+// Resolve fills the url, username and password, explicit settings win over
+// PasswordEnv which wins over the PRTG device credentials in the environment
+func (l Login) Resolve(ctx context.Context) (Login, error) {
+	n := 0
+	for _, s := range []string{l.Password, l.PasswordFile, l.PasswordCommand} {
+		if s != "" {
+			n++
+		}
+	}
+	if n > 1 {
+		return l, fmt.Errorf("use only one of --password, --password-file and --password-command")
+	}
+
+	var err error
+	switch {
+	case l.PasswordFile != "":
+		l.Password, err = readPasswordFile(l.PasswordFile)
+	case l.PasswordCommand != "":
+		l.Password, err = runPasswordCommand(ctx, l.PasswordCommand)
+	case l.Password == "":
+		l.Password = os.Getenv(PasswordEnv)
+		if l.Password == "" {
+			l.Password = os.Getenv(prtgPasswordEnv)
+		}
+	}
+	if err != nil {
+		return l, err
+	}
+
+	if l.Username == "" {
+		l.Username = os.Getenv(prtgUserEnv)
+	}
+	if l.URL == "" {
+		if h := os.Getenv(prtgHostEnv); h != "" {
+			l.URL = "https://" + h + "/sdk"
+		}
+	}
+	return l, nil
+}
+
+func readPasswordFile(fn string) (string, error) {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return "", fmt.Errorf("password file %v", err)
+	}
+	pw := strings.SplitN(string(b), "\n", 2)[0]
+	pw = strings.TrimSuffix(pw, "\r")
+	if pw == "" {
+		return "", fmt.Errorf("password file %v is empty", fn)
+	}
+	return pw, nil
+}
+
+func runPasswordCommand(ctx context.Context, command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("password command %v %v", err, strings.TrimSpace(stderr.String()))
+	}
+	pw := strings.TrimRight(string(out), "\r\n")
+	if pw == "" {
+		return "", fmt.Errorf("password command printed nothing")
+	}
+	return pw, nil
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoginResolve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prtgvmware")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	pwFile := filepath.Join(dir, "pw")
+	if err = ioutil.WriteFile(pwFile, []byte("filepass\r\nsecond line\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	emptyFile := filepath.Join(dir, "empty")
+	if err = ioutil.WriteFile(emptyFile, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	echo := "echo cmdpass"
+
+	prtgEnv := map[string]string{
+		prtgHostEnv:          "vc1.local",
+		prtgUserEnv:          "prtg",
+		"prtg_windowsdomain": "CORP",
+		prtgPasswordEnv:      "prtgpass",
+	}
+	tests := []struct {
+		name    string
+		env     map[string]string
+		l       Login
+		want    Login
+		wantErr bool
+	}{
+		{"flags only", nil, Login{URL: "https://vc/sdk", Username: "u", Password: "p"}, Login{URL: "https://vc/sdk", Username: "u", Password: "p"}, false},
+		{"prtg environment", prtgEnv, Login{}, Login{URL: "https://vc1.local/sdk", Username: "prtg", Password: "prtgpass"}, false},
+		{"flags beat prtg environment", prtgEnv, Login{URL: "https://vc/sdk", Username: "u", Password: "p"}, Login{URL: "https://vc/sdk", Username: "u", Password: "p"}, false},
+		{"upn user", map[string]string{prtgUserEnv: "administrator@vsphere.local", "prtg_windowsdomain": "CORP"}, Login{}, Login{Username: "administrator@vsphere.local"}, false},
+		{"password env beats prtg", map[string]string{PasswordEnv: "envpass", prtgPasswordEnv: "prtgpass"}, Login{}, Login{Password: "envpass"}, false},
+		{"password file", prtgEnv, Login{PasswordFile: pwFile}, Login{URL: "https://vc1.local/sdk", Username: "prtg", Password: "filepass", PasswordFile: pwFile}, false},
+		{"password command", nil, Login{PasswordCommand: echo}, Login{Password: "cmdpass", PasswordCommand: echo}, false},
+		{"missing file", nil, Login{PasswordFile: filepath.Join(dir, "nope")}, Login{}, true},
+		{"empty file", nil, Login{PasswordFile: emptyFile}, Login{}, true},
+		{"failing command", nil, Login{PasswordCommand: "exit 3"}, Login{}, true},
+		{"two sources", nil, Login{Password: "p", PasswordFile: pwFile}, Login{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range []string{PasswordEnv, prtgHostEnv, prtgUserEnv, "prtg_windowsdomain", prtgPasswordEnv} {
+				_ = os.Unsetenv(k)
+			}
+			for k, v := range tt.env {
+				_ = os.Setenv(k, v)
+			}
+			got, err := tt.l.Resolve(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("Resolve() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	for k := range prtgEnv {
+		_ = os.Unsetenv(k)
+	}
+	_ = os.Unsetenv(PasswordEnv)
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"github.com/vmware/govmomi/simulator"
+	"testing"
+)
+
+// newSim starts a simulator for model, setup changes the model before its inventory is created,
+// the returned func stops the simulator
+func newSim(t *testing.T, model *simulator.Model, setup func(*simulator.Model)) (*simulator.Server, func()) {
+	if setup != nil {
+		setup(model)
+	}
+	if err := model.Create(); err != nil {
+		model.Remove()
+		t.Fatal(err)
+	}
+	model.Service.RegisterEndpoints = true
+	s := model.Service.NewServer()
+	return s, func() {
+		s.Close()
+		model.Remove()
+	}
+}
+
+// newSimClient logs in to a vCenter simulator, the returned func logs out and stops it
+func newSimClient(t *testing.T, setup func(*simulator.Model)) (Client, func()) {
+	s, stop := newSim(t, simulator.VPX(), setup)
+	c, err := NewClient(context.Background(), s.URL, "user", "pass", false, TLSOptions{Insecure: true})
+	if err != nil {
+		stop()
+		t.Fatal(err)
+	}
+	return c, func() {
+		_ = c.Logout()
+		stop()
+	}
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"fmt"
+	ps "github.com/PRTG/go-prtg-sensor-api"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+	"time"
+)
+
+// PodSummary stats for a datastore cluster and its member datastores
+func (c *Client) PodSummary(ctx context.Context, name, moid string, js bool) (err error) {
+	start := time.Now()
+	ictx, cancel := c.inventoryCtx(ctx)
+	defer cancel()
+
+	id := types.ManagedObjectReference{
+		Type:  "StoragePod",
+		Value: moid,
+	}
+	if moid == "" {
+		id, err = c.findOne(ictx, name, id.Type)
+		if err != nil {
+			return PhaseError(ictx, "inventory", err)
+		}
+	}
+	pod := mo.StoragePod{}
+	err = c.retrieveOne(ictx, id, []string{"name", "summary", "podStorageDrsEntry", "childEntity", "overallStatus"}, &pod)
+	if err != nil {
+		return PhaseError(ictx, "inventory", errCheck(name, id, fmt.Errorf("storage pod v.properties %v", err)))
+	}
+	var members []mo.Datastore
+	err = c.retrieve(ictx, pod.ChildEntity, []string{"name", "summary"}, &members)
+	if err != nil {
+		return PhaseError(ictx, "inventory", fmt.Errorf("storage pod datastores %v", err))
+	}
+
+	pr := c.prtgData(id, pod.Name)
+	_ = pr.add(managedEntityStatus(pod.OverallStatus), ps.SensorChannel{Channel: "Overall Status", Unit: "Custom", CustomUnit: "Custom", ValueLookup: lookupStatus})
+	_ = pr.add(len(members), ps.SensorChannel{Channel: "Datastores", Unit: "Count"})
+
+	var capacity, free int64
+	for _, ds := range members {
+		capacity += ds.Summary.Capacity
+		free += ds.Summary.FreeSpace
+		if !ds.Summary.Accessible {
+			pr.failures = append(pr.failures, ds.Name)
+		}
+		if one := ds.Summary.Capacity / 100; one > 0 {
+			_ = pr.addSample(ds.Summary.FreeSpace/one, ps.SensorChannel{Channel: "Free space (Percent) " + ds.Name, Unit: "Percent", LimitMinWarning: "20", LimitMinError: "10",
+				LimitWarningMsg: "Warning Low Space", LimitErrorMsg: "Critical disk space", LimitMode: "1"}, "member free percent", ds.Name)
+		}
+		_ = pr.addSample(boolToInt(ds.Summary.MaintenanceMode != string(types.DatastoreSummaryMaintenanceModeStateNormal)),
+			ps.SensorChannel{Channel: "Maintenance Mode " + ds.Name, Unit: "Custom", ValueLookup: lookupMaintenanceMode}, "member maintenance mode", ds.Name)
+	}
+	if len(pr.failures) > 0 {
+		pr.text = fmt.Sprintf("%v of %v datastores inaccessible", len(pr.failures), len(members))
+	}
+	// the pod summary is what vcenter places vms against, members are summed when it is missing
+	if pod.Summary != nil && pod.Summary.Capacity > 0 {
+		capacity, free = pod.Summary.Capacity, pod.Summary.FreeSpace
+	}
+	_ = pr.add(capacity, ps.SensorChannel{Channel: "Total capacity", Unit: "BytesDisk", VolumeSize: "KiloByte"})
+	_ = pr.add(free, ps.SensorChannel{Channel: "Free Bytes", Unit: "BytesDisk", VolumeSize: "KiloByte"})
+	if one := capacity / 100; one > 0 {
+		_ = pr.add(free/one, ps.SensorChannel{Channel: "Free space (Percent)", Unit: "Percent", DecimalMode: "1", LimitMinWarning: "20", LimitMinError: "10",
+			LimitWarningMsg: "Warning Low Space", LimitErrorMsg: "Critical disk space", LimitMode: "1"})
+	}
+
+	if e := pod.PodStorageDrsEntry; e != nil {
+		cfg := e.StorageDrsConfig.PodConfig
+		_ = pr.add(boolToInt(cfg.Enabled), ps.SensorChannel{Channel: "Storage DRS Enabled", Unit: "Custom", ValueLookup: lookupEnabled})
+		if cfg.Enabled {
+			_ = pr.add(storageDrsBehavior(cfg.DefaultVmBehavior), ps.SensorChannel{Channel: "Storage DRS Automation Level", Unit: "Custom", ValueLookup: lookupDrsBehavior})
+		}
+		_ = pr.add(len(e.Recommendation), ps.SensorChannel{Channel: "Pending Recommendations", Unit: "Count", LimitMaxWarning: "0", LimitWarningMsg: "storage DRS recommendations waiting to be applied", LimitMode: "1"})
+	}
+
+	_ = pr.print(time.Since(start), js)
+	return nil
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+	"testing"
+	"time"
+)
+
+func TestPodSummary(t *testing.T) {
+	c, stop := newSimClient(t, func(m *simulator.Model) {
+		m.Datastore = 2
+	})
+	defer stop()
+	ctx := context.Background()
+
+	finder := find.NewFinder(c.c, false)
+	dc, err := finder.Datacenter(ctx, "DC0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	finder.SetDatacenter(dc)
+	folders, err := dc.Folders(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod, err := folders.DatastoreFolder.CreateStoragePod(ctx, "pod0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dss, err := finder.DatastoreList(ctx, "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := make([]types.ManagedObjectReference, 0, len(dss))
+	for _, ds := range dss {
+		refs = append(refs, ds.Reference())
+	}
+	task, err := pod.MoveInto(ctx, refs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := task.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var got Result
+	c.SetSink(func(r Result) error {
+		got = r
+		return nil
+	})
+	err = c.PodSummary(ctx, "pod0", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]string)
+	members := 0
+	for _, s := range got.Samples {
+		values[s.Channel] = s.Value
+		if s.Family == "member free percent" {
+			members++
+		}
+	}
+	if values["Datastores"] != "2" || members != 2 || values["Maintenance Mode "+dss[0].Name()] != "0" {
+		t.Errorf("channels %v", values)
+	}
+	if v, ok := values["Free space (Percent)"]; !ok || v == "0" {
+		t.Errorf("no pod free space in %v", values)
+	}
+
+	// a tag on the pod selects the pod and its datastores
+	ids, err := c.getChildIds(ctx, pod.Reference())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3 || ids[0] != pod.Reference() {
+		t.Errorf("children %v", ids)
+	}
+	tm := NewTagMap()
+	tm.add(pod.Reference(), "storage")
+	names, err := newMoidNames(ctx, &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := c.obMeta(tm, names, time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, it := range meta.Items {
+		found = found || it.Name == "Pod pod0"
+	}
+	if !found {
+		t.Errorf("no pod sensor in %+v", meta.Items)
+	}
+}
@@ -20,7 +20,6 @@ import (
 	"context"
 	"fmt"
 	"github.com/vmware/govmomi/vapi/tags"
-	"github.com/vmware/govmomi/view"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 	"strings"
@@ -77,10 +76,10 @@ func (t *TagMap) check(vm string, tag []string) bool {
 	return false
 }
 
-func (c *Client) list(tagIds []string, tm *TagMap) (err error) {
+func (c *Client) list(ctx context.Context, tagIds []string, tm *TagMap) (err error) {
 
 	for _, tag := range tagIds {
-		err = c.getObjIds(tag, tm)
+		err = c.getObjIds(ctx, tag, tm)
 		if err != nil {
 			return err
 		}
@@ -89,16 +88,20 @@ func (c *Client) list(tagIds []string, tm *TagMap) (err error) {
 	return
 }
 
-func (c *Client) getObjIds(tag string, tm *TagMap) (err error) {
-	ctx := context.Background()
+func (c *Client) getObjIds(ctx context.Context, tag string, tm *TagMap) (err error) {
 	if c.r == nil {
+		if !c.c.IsVC() {
+			return fmt.Errorf("tags need the vcenter tag service, use names or folders with standalone hosts")
+		}
 		return fmt.Errorf("could not connect using rest client, check vcenter logs")
 	}
-	manager := tags.NewManager(c.r)
-
 	workingData := make([]types.ManagedObjectReference, 0, 10)
 
-	objs, err := manager.GetAttachedObjectsOnTags(ctx, []string{tag})
+	var objs []tags.AttachedObjects
+	err = c.retry(ctx, func() (err error) {
+		objs, err = tags.NewManager(c.r).GetAttachedObjectsOnTags(ctx, []string{tag})
+		return
+	})
 	if err != nil {
 		if strings.Contains(err.Error(), "404 Not Found") {
 			return nil
@@ -115,7 +118,7 @@ func (c *Client) getObjIds(tag string, tm *TagMap) (err error) {
 	}
 	for _, obj := range objs[0].ObjectIDs {
 		workingData = append(workingData, obj.Reference())
-		rtn, err := c.getChildIds(obj.Reference())
+		rtn, err := c.getChildIds(ctx, obj.Reference())
 		if err != nil {
 			return fmt.Errorf("getChildIds  %v", err)
 		}
@@ -129,59 +132,84 @@ func (c *Client) getObjIds(tag string, tm *TagMap) (err error) {
 
 }
 
-func (c *Client) getChildIds(id types.ManagedObjectReference) (rtnData []types.ManagedObjectReference, err error) {
+func (c *Client) getChildIds(ctx context.Context, id types.ManagedObjectReference) (rtnData []types.ManagedObjectReference, err error) {
 	rtnData = make([]types.ManagedObjectReference, 0, 10)
 
-	ctx := context.Background()
-	//	ctx, _ = context.WithTimeout(ctx, 10*time.Second)
-	m := view.NewManager(c.c)
-	v, err := m.CreateContainerView(ctx, c.c.ServiceContent.RootFolder, []string{id.Type}, true)
-	if err != nil {
-		err = fmt.Errorf("CreateContainerView %v", err)
-		return nil, err
-
-	}
-	defer func() { _ = v.Destroy(ctx) }()
 	switch id.Type {
-	case "VirtualMachine", "Datastore", "VmwareDistributedVirtualSwitch", "DistributedVirtualPortgroup":
+	case "VirtualMachine", "Datastore", "VmwareDistributedVirtualSwitch", "DistributedVirtualSwitch", "DistributedVirtualPortgroup":
 		return []types.ManagedObjectReference{id}, nil
 	case "HostSystem":
 
 		var wd mo.HostSystem
-		err = v.Properties(ctx, id, []string{"vm", "datastore", "network"}, &wd)
+		err = c.retrieveOne(ctx, id, []string{"vm", "datastore", "network"}, &wd)
 		if err != nil {
 			err = fmt.Errorf("vm Properties %v", err)
 			return nil, err
 
 		}
 		rtnData = append(rtnData, wd.Vm...)
-		rtnData = append(rtnData, wd.Network...)
+		rtnData = append(rtnData, withoutPortgroups(wd.Network)...)
 		rtnData = append(rtnData, wd.Datastore...)
 
 	case "VirtualApp":
 		var wd mo.VirtualApp
-		err = v.Properties(ctx, id, []string{"vm", "datastore", "network"}, &wd)
+		err = c.retrieveOne(ctx, id, []string{"vm", "datastore", "network"}, &wd)
 		if err != nil {
 			return nil, errCheck("", id, fmt.Errorf("vapp v.properties %v", err))
 		}
 
 		rtnData = append(rtnData, wd.Vm...)
-		rtnData = append(rtnData, wd.Network...)
+		rtnData = append(rtnData, withoutPortgroups(wd.Network)...)
 		rtnData = append(rtnData, wd.Datastore...)
 
+	case "ComputeResource":
+		// standalone hosts, including the host itself when connected directly to ESXi
+		var wd mo.ComputeResource
+		err = c.retrieveOne(ctx, id, []string{"network", "host", "datastore"}, &wd)
+		if err != nil {
+			return nil, errCheck("compute resource", id, fmt.Errorf("compute resource v.properties %v", err))
+		}
+		rtnData = append(rtnData, withoutPortgroups(wd.Network)...)
+		for _, h := range wd.Host {
+			d, err := c.getChildIds(ctx, h)
+			if err != nil {
+				return nil, err
+			}
+			rtnData = append(rtnData, h)
+			rtnData = append(rtnData, d...)
+		}
+		rtnData = append(rtnData, wd.Datastore...)
+
+	case "ResourcePool":
+		var wd mo.ResourcePool
+		err = c.retrieveOne(ctx, id, []string{"vm", "resourcePool"}, &wd)
+		if err != nil {
+			return nil, errCheck("resource pool", id, fmt.Errorf("resource pool v.properties %v", err))
+		}
+		rtnData = append(rtnData, id)
+		rtnData = append(rtnData, wd.Vm...)
+		for _, p := range wd.ResourcePool {
+			d, err := c.getChildIds(ctx, p)
+			if err != nil {
+				return nil, err
+			}
+			rtnData = append(rtnData, d...)
+		}
+
 	case "ClusterComputeResource":
 		var wd mo.ClusterComputeResource
-		err = v.Properties(ctx, id, []string{"network", "host", "datastore"}, &wd)
+		err = c.retrieveOne(ctx, id, []string{"network", "host", "datastore"}, &wd)
 		if err != nil {
 			return nil, errCheck("cluster", id, fmt.Errorf("cluster v.properties %v", err))
 		}
-		rtnData = append(rtnData, wd.Network...)
+		rtnData = append(rtnData, id)
+		rtnData = append(rtnData, withoutPortgroups(wd.Network)...)
 		rtnData = append(rtnData, wd.Host...)
 		rtnData = append(rtnData, wd.Datastore...)
 
 	case "Datacenter":
 		var wd mo.Datacenter
-		err = v.Properties(ctx, id, []string{"hostFolder", "datastoreFolder", "networkFolder"}, &wd)
+		err = c.retrieveOne(ctx, id, []string{"hostFolder", "datastoreFolder", "networkFolder"}, &wd)
 		if err != nil {
 			return nil, errCheck("ds", id, fmt.Errorf("ds v.properties %v", err))
 		}
@@ -189,7 +217,7 @@ func (c *Client) getChildIds(id types.ManagedObjectReference) (rtnData []types.M
 
 		x = append(x, wd.HostFolder, wd.DatastoreFolder, wd.NetworkFolder)
 		for _, v := range x {
-			d, err := c.getChildIds(v)
+			d, err := c.getChildIds(ctx, v)
 			if err != nil {
 				return nil, err
 			}
@@ -197,21 +225,42 @@ func (c *Client) getChildIds(id types.ManagedObjectReference) (rtnData []types.M
 		}
 	case "Folder":
 		var wd mo.Folder
-		err = v.Properties(ctx, id, []string{"childType", "childEntity"}, &wd)
+		err = c.retrieveOne(ctx, id, []string{"childType", "childEntity"}, &wd)
 		if err != nil {
 			return nil, errCheck("folder", id, fmt.Errorf("folder v.properties %v", err))
 		}
-		for _, id := range wd.ChildEntity {
-			d, err := c.getChildIds(id)
+		for _, id := range withoutPortgroups(wd.ChildEntity) {
+			d, err := c.getChildIds(ctx, id)
 			if err != nil {
 				return nil, err
 			}
 			rtnData = append(rtnData, d...)
 		}
-	case "StoragePod", "Network":
+	case "StoragePod":
+		// datastore clusters stand for their member datastores
+		var wd mo.StoragePod
+		err = c.retrieveOne(ctx, id, []string{"childEntity"}, &wd)
+		if err != nil {
+			return nil, errCheck("storage pod", id, fmt.Errorf("storage pod v.properties %v", err))
+		}
+		rtnData = append(rtnData, id)
+		rtnData = append(rtnData, wd.ChildEntity...)
+	case "Network":
 	default:
 		printJSON(false, "getChildIds, missed type, Please log an issue on Github", id.Type)
 		return nil, nil
 	}
 	return
 }
+
+// withoutPortgroups drops distributed portgroups from the networks of a host, cluster or vapp and the children of a folder,
+// portgroups only get a sensor when they are selected themselves
+func withoutPortgroups(refs []types.ManagedObjectReference) []types.ManagedObjectReference {
+	rtn := make([]types.ManagedObjectReference, 0, len(refs))
+	for _, r := range refs {
+		if r.Type != "DistributedVirtualPortgroup" {
+			rtn = append(rtn, r)
+		}
+	}
+	return rtn
+}
@@ -17,6 +17,7 @@
 package app
 
 import (
+	"context"
 	"github.com/vmware/govmomi/vim25/types"
 	"testing"
 )
@@ -34,14 +35,14 @@ func TestClient_tagList(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			c, err := NewClient(u, user, passwd, true)
+			c, err := NewClient(context.Background(), u, user, passwd, true, TLSOptions{Insecure: true})
 			if err != nil {
 				t.Fatal("cant get client")
 			}
 			defer func() { _ = c.Logout() }()
 
 			gotRtnMap := NewTagMap()
-			err = c.list(tt.tagIds, gotRtnMap)
+			err = c.list(context.Background(), tt.tagIds, gotRtnMap)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("list() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -98,7 +99,7 @@ func Test_tagMap_check(t *testing.T) {
 		{"5", "vm-19", "PRTG", false, true},
 	}
 
-	c, err := NewClient(u, user, passwd, true)
+	c, err := NewClient(context.Background(), u, user, passwd, true, TLSOptions{Insecure: true})
 	if err != nil {
 		t.Fatal("cant get client")
 	}
@@ -107,7 +108,7 @@ func Test_tagMap_check(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			ttagMap := NewTagMap()
-			err := c.list([]string{tt.tag}, ttagMap)
+			err := c.list(context.Background(), []string{tt.tag}, ttagMap)
 			if (err != nil) && !tt.wantErr {
 				t.Fatalf("taglist error %v", err)
 			}
@@ -132,13 +133,13 @@ func TestClient_GetVmsOnTags(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			c, err := NewClient(u, user, passwd, true)
+			c, err := NewClient(context.Background(), u, user, passwd, true, TLSOptions{Insecure: true})
 			if err != nil {
 				t.Fatal("cant get client")
 			}
 			defer func() { _ = c.Logout() }()
 
-			if err := c.getObjIds(tt.tag, gotRtnMap); (err != nil) != tt.wantErr {
+			if err := c.getObjIds(context.Background(), tt.tag, gotRtnMap); (err != nil) != tt.wantErr {
 				t.Errorf("GetVmsOnTags() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
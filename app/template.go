@@ -18,6 +18,7 @@ package app
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io/ioutil"
@@ -41,7 +42,7 @@ type Devicetemplate struct {
 }
 
 // NewDeviceTemplate creates a new base template
-func NewDeviceTemplate(Age time.Duration, Tags string) *Devicetemplate {
+func NewDeviceTemplate(Age time.Duration, Tags, profile string) *Devicetemplate {
 	d := &Devicetemplate{
 		XMLName:  xml.Name{},
 		ID:       "customexexml",
@@ -53,13 +54,36 @@ func NewDeviceTemplate(Age time.Duration, Tags string) *Devicetemplate {
 
 	// add a ping sensor
 
-	d.Create = append(d.Create, pingSensor, port443(), snapShotSensor(Age, Tags))
+	d.Create = append(d.Create, pingSensor, port443(), snapShotSensor(Age, Tags, profile))
 	return d
 }
 func (dev *Devicetemplate) add(cr Check) error {
 	dev.Create = append(dev.Create, cr)
 	return nil
 }
+
+// pageSnapshots replaces the snapshot sensor with one sensor per page of n pages
+func (dev *Devicetemplate) pageSnapshots(n int) {
+	if n <= 1 {
+		return
+	}
+	create := make([]Check, 0, len(dev.Create)+n)
+	for _, c := range dev.Create {
+		if c.ID != "snapshots" {
+			create = append(create, c)
+			continue
+		}
+		for i := 1; i <= n; i++ {
+			p := c
+			p.ID = fmt.Sprintf("snapshots page %v", i)
+			p.Createdata.Name = fmt.Sprintf("%v page %v", c.Createdata.Name, i)
+			p.Createdata.Exeparams = fmt.Sprintf("%v --page %v", c.Createdata.Exeparams, i)
+			create = append(create, p)
+		}
+	}
+	dev.Create = create
+}
+
 func (dev *Devicetemplate) save(tplate string) (err error) {
 	ou, err := xml.MarshalIndent(dev, "", "  ")
 	if err != nil {
@@ -102,9 +126,11 @@ type Createdata struct {
 	Timeout            string `xml:"timeout,omitempty"`
 	Exefile            string `xml:"exefile,omitempty"`
 	Exeparams          string `xml:"exeparams,omitempty"`
+	Environment        string `xml:"environment,omitempty"`
 	Name               string `xml:"name,omitempty"`
 	Mutex              string `xml:"mutexname,omitempty"`
 	Decimaldigits      string `xml:"decimaldigits,omitempty"`
+	Token              string `xml:"httppushtoken,omitempty"`
 }
 
 func newCreate(id, params, tags, intervalSecs string) Check {
@@ -155,7 +181,32 @@ func port443() Check {
 	return c
 }
 
-func snapShotSensor(Age time.Duration, Tags string) Check {
+// sensorCreds returns the connection parameters used by generated sensors, without a profile
+// sensors read the device credentials PRTG puts in their environment so no password is in exeparams
+func sensorCreds(profile string) string {
+	if profile != "" {
+		return " --profile " + profile
+	}
+	return ""
+}
+
+// sensorEnv turns on "Set placeholders as environment values" for sensors using the device credentials
+func sensorEnv(profile string) string {
+	if profile != "" {
+		return ""
+	}
+	return "1"
+}
+
+// tagArg limits snapshot sensors to tagged vms, without tags every vm is reported
+func tagArg(tags string) string {
+	if tags == "" {
+		return ""
+	}
+	return " --tags " + tags
+}
+
+func snapShotSensor(Age time.Duration, Tags, profile string) Check {
 	name := fmt.Sprintf("snapshots older than %v hours", Age.Hours())
 	c := Check{
 		ID:       "snapshots",
@@ -164,20 +215,25 @@ func snapShotSensor(Age time.Duration, Tags string) Check {
 		Requires: "ping",
 		Createdata: Createdata{Name: name, Tags: Tags, Errorintervalsdown: "5",
 			Autoacknowledge: "1", Priority: "3", Exefile: filepath.Base(os.Args[0]), Mutex: "prtgvmware",
-			Exeparams: fmt.Sprintf("snapshots -U https://%%host/sdk -u %%windowsuser -p %%windowspassword --snapAge %v --tags %v --maxWarn 1 --maxErr 3", Age, Tags),
+			Exeparams:   fmt.Sprintf("snapshots%v --snapAge %v%v --maxWarn 1 --maxErr 3", sensorCreds(profile), Age, tagArg(Tags)),
+			Environment: sensorEnv(profile),
 		},
 	}
 	return c
 }
 
 // GenTemplate creates a template for use with single time ingestion / manual reset
-func GenTemplate(tags []string, Age time.Duration, tplate string) error {
+func GenTemplate(sel Selection, Age time.Duration, tplate, profile string) error {
 	//fmt.Println(basetemplate)
-	creds := "-U https://%Host/sdk -u %windowsuser -p %windowspassword"
-	d := NewDeviceTemplate(Age, strings.Join(tags, ","))
+	if sel.Empty() {
+		return fmt.Errorf("nothing to discover, provide tags, names or folders")
+	}
+	creds := sensorCreds(profile)
+	d := NewDeviceTemplate(Age, strings.Join(sel.Tags, ","), profile)
 
-	ch1 := fmt.Sprintf("metascan %v --snapAge %v --tags %v", creds, Age, strings.Join(tags, ","))
-	ch := newCreate("metascan", ch1, strings.Join(tags, ","), "300")
+	ch1 := fmt.Sprintf("metascan%v --snapAge %v %v", creds, Age, sel.args())
+	ch := newCreate("metascan", ch1, strings.Join(sel.Tags, ","), "300")
+	ch.Createdata.Environment = sensorEnv(profile)
 	err := d.add(ch)
 	if err != nil {
 		return fmt.Errorf("failed to add check %v", err)
@@ -193,26 +249,31 @@ func GenTemplate(tags []string, Age time.Duration, tplate string) error {
 }
 
 // DynTemplate creates a template for regular ingestion by PRTG
-func (c *Client) DynTemplate(tags []string, Age time.Duration, tplate string) error {
-	d := NewDeviceTemplate(Age, strings.Join(tags, ","))
+func (c *Client) DynTemplate(ctx context.Context, sel Selection, Age time.Duration, tplate, profile string) error {
+	tags := sel.Tags
+	d := NewDeviceTemplate(Age, strings.Join(tags, ","), profile)
 
+	ctx, cancel := c.inventoryCtx(ctx)
+	defer cancel()
 	tm := NewTagMap()
-	err := c.list(tags, tm)
+	err := c.discover(ctx, sel, tm)
 	if err != nil {
-		return err
+		return PhaseError(ctx, "inventory", err)
 	}
-	for _, tag := range tags {
-		err := c.getObjIds(tag, tm)
-		if err != nil {
-			return fmt.Errorf("%v", err)
-		}
+	moidNames, err := newMoidNames(ctx, c)
+	if err != nil {
+		return PhaseError(ctx, "inventory", err)
 	}
-	moidNames := newMoidNames(c)
 
-	meta, err := c.obMeta(tm, moidNames, Age)
+	meta, err := c.obMeta(tm, moidNames, Age, profile)
+	if err != nil {
+		return err
+	}
+	meta.Items, err = c.pageItems(ctx, meta.Items, moidNames)
 	if err != nil {
 		return err
 	}
+	d.pageSnapshots(snapshotPages(tm, moidNames, tags))
 
 	for _, v := range meta.Items {
 		c := Check{
@@ -222,7 +283,7 @@ func (c *Client) DynTemplate(tags []string, Age time.Duration, tplate string) er
 			Requires: "ping",
 			Createdata: Createdata{Name: v.Name, Tags: strings.Join(tags, ","), Errorintervalsdown: "5",
 				Autoacknowledge: v.Autoacknowledge, Priority: "3", Exefile: filepath.Base(os.Args[0]), Mutex: "prtgvmware",
-				Exeparams: v.Params,
+				Exeparams: v.Params, Environment: v.Environment,
 			},
 		}
 		err = d.add(c)
@@ -17,6 +17,7 @@
 package app
 
 import (
+	"context"
 	"testing"
 	"time"
 )
@@ -30,7 +31,7 @@ func TestGenTemplate(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			err := GenTemplate([]string{"prtg"}, time.Second, "deleteme")
+			err := GenTemplate(Selection{Tags: []string{"prtg"}}, time.Second, "deleteme", "")
 			if err != nil {
 				t.Fatalf("failed %v", err)
 			}
@@ -45,13 +46,13 @@ func TestClient_DynTemplate(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			c, err := NewClient(u, user, passwd, true)
+			c, err := NewClient(context.Background(), u, user, passwd, true, TLSOptions{Insecure: true})
 			if err != nil {
 				t.Fatalf("failed %v", err)
 			}
 			defer func() { _ = c.Logout() }()
 
-			err = c.DynTemplate([]string{"PRTG"}, time.Second, "deleteme")
+			err = c.DynTemplate(context.Background(), Selection{Tags: []string{"PRTG"}}, time.Second, "deleteme", "")
 			if err != nil {
 				t.Fatalf("failed %v", err)
 			}
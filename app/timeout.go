@@ -0,0 +1,62 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// logoutTimeout bounds cleanup after the sensor budget may already be spent
+const logoutTimeout = 5 * time.Second
+
+// Timeouts are per phase budgets inside the overall sensor deadline, zero leaves a phase bound only by the deadline
+type Timeouts struct {
+	Login     time.Duration
+	Inventory time.Duration
+	Metrics   time.Duration
+}
+
+// SetTimeouts sets the inventory and metrics budgets used by sensor methods
+func (c *Client) SetTimeouts(t Timeouts) {
+	c.timeouts = t
+}
+
+// WithBudget limits ctx to d, a zero budget only adds cancellation
+func WithBudget(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+func (c *Client) inventoryCtx(ctx context.Context) (context.Context, context.CancelFunc) {
+	return WithBudget(ctx, c.timeouts.Inventory)
+}
+
+func (c *Client) metricsCtx(ctx context.Context) (context.Context, context.CancelFunc) {
+	return WithBudget(ctx, c.timeouts.Metrics)
+}
+
+// PhaseError replaces an error caused by an expired deadline with one naming the phase and the flags that raise it
+func PhaseError(ctx context.Context, phase string, err error) error {
+	if err == nil || ctx.Err() != context.DeadlineExceeded {
+		return err
+	}
+	return fmt.Errorf("%v timed out, raise --timeout or --%vTimeout", phase, phase)
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPhaseError(t *testing.T) {
+	expired, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-expired.Done()
+	cancelled, cancel2 := context.WithCancel(context.Background())
+	cancel2()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want string
+	}{
+		{"no error", expired, nil, ""},
+		{"deadline", expired, fmt.Errorf("Post: context deadline exceeded"), "metrics timed out, raise --timeout or --metricsTimeout"},
+		{"cancelled", cancelled, fmt.Errorf("context canceled"), "context canceled"},
+		{"live context", context.Background(), fmt.Errorf("not found"), "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := PhaseError(tt.ctx, "metrics", tt.err)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("PhaseError() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithBudget(t *testing.T) {
+	ctx, cancel := WithBudget(context.Background(), 0)
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("zero budget set a deadline")
+	}
+	ctx, cancel = WithBudget(context.Background(), time.Minute)
+	defer cancel()
+	if _, ok := ctx.Deadline(); !ok {
+		t.Error("budget did not set a deadline")
+	}
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/vmware/govmomi/vim25/soap"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// TLSOptions controls how the vcenter certificate is verified, the zero value uses the system roots
+type TLSOptions struct {
+	// Insecure skips all certificate checks
+	Insecure bool
+	// CAFile holds PEM encoded roots, multiple files are separated by the OS path list separator
+	CAFile string
+	// Thumbprint pins the server certificate, SHA1 as shown by vSphere or SHA256
+	Thumbprint string
+}
+
+func (t TLSOptions) String() string {
+	return fmt.Sprintf("%v|%v|%v", t.Insecure, t.CAFile, normThumbprint(t.Thumbprint))
+}
+
+// thumbprintError is returned when the server certificate doesn't match the pinned thumbprint
+type thumbprintError struct {
+	want, got string
+}
+
+func (e thumbprintError) Error() string {
+	return fmt.Sprintf("certificate thumbprint %v does not match %v", e.got, e.want)
+}
+
+// normThumbprint strips separators so AA:BB and aabb compare equal
+func normThumbprint(s string) string {
+	s = strings.ToUpper(s)
+	return strings.Map(func(r rune) rune {
+		if r == ':' || r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func thumbprintSHA256(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// config builds the tls config used to talk to host
+func (t TLSOptions) config(host string) (*tls.Config, error) {
+	if t.Insecure {
+		return &tls.Config{InsecureSkipVerify: true}, nil
+	}
+
+	cfg := &tls.Config{ServerName: host}
+	if t.CAFile != "" {
+		pool := x509.NewCertPool()
+		for _, fn := range filepath.SplitList(t.CAFile) {
+			pem, err := ioutil.ReadFile(fn)
+			if err != nil {
+				return nil, fmt.Errorf("ca file %v", err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("ca file %v holds no PEM certificates", fn)
+			}
+		}
+		cfg.RootCAs = pool
+	}
+
+	want := normThumbprint(t.Thumbprint)
+	if want == "" {
+		return cfg, nil
+	}
+	switch len(want) {
+	case sha1.Size * 2, sha256.Size * 2:
+	default:
+		return nil, fmt.Errorf("thumbprint %v is neither SHA1 nor SHA256", t.Thumbprint)
+	}
+
+	// a pinned certificate is trusted without a chain unless a ca file was also given
+	roots := cfg.RootCAs
+	cfg.InsecureSkipVerify = true
+	cfg.VerifyPeerCertificate = func(raw [][]byte, _ [][]*x509.Certificate) error {
+		if len(raw) == 0 {
+			return fmt.Errorf("server sent no certificate")
+		}
+		certs := make([]*x509.Certificate, 0, len(raw))
+		for _, b := range raw {
+			cert, err := x509.ParseCertificate(b)
+			if err != nil {
+				return err
+			}
+			certs = append(certs, cert)
+		}
+		got := normThumbprint(soap.ThumbprintSHA1(certs[0]))
+		if len(want) == sha256.Size*2 {
+			got = thumbprintSHA256(certs[0])
+		}
+		if got != want {
+			return thumbprintError{want: want, got: got}
+		}
+		if roots == nil {
+			return nil
+		}
+		inter := x509.NewCertPool()
+		for _, c := range certs[1:] {
+			inter.AddCert(c)
+		}
+		_, err := certs[0].Verify(x509.VerifyOptions{DNSName: host, Roots: roots, Intermediates: inter})
+		return err
+	}
+	return cfg, nil
+}
+
+// apply sets the tls config on a soap client, clients created from it with NewServiceClient share the config
+func (t TLSOptions) apply(sc *soap.Client) error {
+	cfg, err := t.config(sc.URL().Hostname())
+	if err != nil {
+		return err
+	}
+	sc.DefaultTransport().TLSClientConfig = cfg
+	return nil
+}
+
+// tlsError explains certificate failures and how to resolve them, other errors are returned as is
+func tlsError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var cert *x509.Certificate
+	var ua x509.UnknownAuthorityError
+	var he x509.HostnameError
+	var ci x509.CertificateInvalidError
+	var te thumbprintError
+	switch {
+	case errors.As(err, &te):
+		return fmt.Errorf("certificate verification failed, %v, update --thumbprint if the certificate was replaced", te)
+	case errors.As(err, &ua):
+		cert = ua.Cert
+	case errors.As(err, &he):
+		cert = he.Certificate
+	case errors.As(err, &ci):
+		cert = ci.Cert
+	default:
+		return err
+	}
+	hint := "trust the issuing CA with --ca-file, pin the certificate with --thumbprint or skip checks with --insecure"
+	if cert != nil {
+		hint = fmt.Sprintf("%v, server thumbprint is %v", hint, soap.ThumbprintSHA1(cert))
+	}
+	return fmt.Errorf("certificate verification failed, %v, %v", unwrapURLError(err), hint)
+}
+
+// unwrapURLError drops the method and url prefix added by net/http
+func unwrapURLError(err error) error {
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		return ue.Err
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package app
+
+import (
+	"encoding/pem"
+	"github.com/vmware/govmomi/vim25/soap"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTLSOptions(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	cert := srv.Certificate()
+
+	dir, err := ioutil.TempDir("", "prtgvmware")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	caFile := filepath.Join(dir, "ca.pem")
+	err = ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sha1 := soap.ThumbprintSHA1(cert)
+	wrong := "00" + sha1[2:]
+
+	tests := []struct {
+		name    string
+		opts    TLSOptions
+		wantErr string
+	}{
+		{"system roots", TLSOptions{}, "--ca-file"},
+		{"insecure", TLSOptions{Insecure: true}, ""},
+		{"ca file", TLSOptions{CAFile: caFile}, ""},
+		{"sha1 thumbprint", TLSOptions{Thumbprint: sha1}, ""},
+		{"sha1 thumbprint lower case", TLSOptions{Thumbprint: strings.ToLower(sha1)}, ""},
+		{"sha256 thumbprint", TLSOptions{Thumbprint: thumbprintSHA256(cert)}, ""},
+		{"wrong thumbprint", TLSOptions{Thumbprint: wrong}, "does not match"},
+		{"thumbprint and ca file", TLSOptions{Thumbprint: sha1, CAFile: caFile}, ""},
+		{"bad thumbprint", TLSOptions{Thumbprint: "AA:BB"}, "neither SHA1 nor SHA256"},
+		{"missing ca file", TLSOptions{CAFile: filepath.Join(dir, "missing.pem")}, "ca file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, _ := url.Parse(srv.URL)
+			sc := soap.NewClient(u, tt.opts.Insecure)
+			err := tt.opts.apply(sc)
+			if err == nil {
+				var res *http.Response
+				res, err = sc.Get(srv.URL)
+				if err == nil {
+					_ = res.Body.Close()
+				}
+				err = tlsError(err)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
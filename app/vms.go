@@ -23,7 +23,6 @@ import (
 	ps "github.com/PRTG/go-prtg-sensor-api"
 	"github.com/juju/fslock"
 	"github.com/vmware/govmomi/object"
-	"github.com/vmware/govmomi/performance"
 	"github.com/vmware/govmomi/property"
 	"github.com/vmware/govmomi/view"
 	"github.com/vmware/govmomi/vim25/mo"
@@ -98,7 +97,7 @@ var (
 
 func getLock(f string, t time.Duration) (lock *fslock.Lock, err error) {
 	lock = fslock.New(f + ".lock")
-	for start := time.Now(); time.Since(start) < t; {
+	for start := time.Now(); time.Since(start) < t; time.Sleep(20 * time.Millisecond) {
 		err = lock.TryLock()
 		if err == nil {
 			return
@@ -121,14 +120,11 @@ func (c *Client) vmTracker(vm, host string) error {
 	}
 	defer func() { _ = lock.Unlock() }()
 
-	vmBytes, err := ioutil.ReadFile(hvmFile)
-	if err != nil {
-		fmt.Println(err)
-	}
+	// a missing or damaged file is replaced, sensor output must not be interrupted
 	var vmMap map[string]vmTracker
-	err = json.Unmarshal(vmBytes, &vmMap)
-	if err != nil {
-		fmt.Println(err)
+	vmBytes, err := ioutil.ReadFile(hvmFile)
+	if err == nil {
+		_ = json.Unmarshal(vmBytes, &vmMap)
 	}
 
 	if vmMap == nil {
@@ -148,91 +144,63 @@ func (c *Client) vmTracker(vm, host string) error {
 	return err
 }
 
-func (c *Client) findOne(name, vmwareType string) (moid types.ManagedObjectReference, err error) {
-	ctx := context.Background()
-	ctx, _ = context.WithTimeout(ctx, 5*time.Second)
-
-	v, err := c.m.CreateContainerView(c.ctx, c.c.ServiceContent.RootFolder, []string{vmwareType}, true)
-	if err != nil {
-		err = fmt.Errorf("failed to create container %v %v %v", name, vmwareType, err)
-		return
+func (c *Client) findOne(ctx context.Context, name, vmwareType string) (moid types.ManagedObjectReference, err error) {
+	if ref, ok := c.cachedFind(name, vmwareType); ok {
+		return ref, nil
 	}
-	defer func() { _ = v.Destroy(ctx) }()
 	switch vmwareType {
-	case "HostSystem":
-		ol := mo.HostSystem{}
-		err = v.RetrieveWithFilter(ctx, []string{"HostSystem"}, []string{"name"}, &ol, property.Filter{"name": name})
-		if err != nil {
-			err = fmt.Errorf("failed to find %v %v %v", name, vmwareType, err)
-			return
-		}
-		moid = ol.Reference()
-
-	case "VirtualMachine":
-		ol := mo.VirtualMachine{}
-		err = v.RetrieveWithFilter(ctx, []string{"VirtualMachine"}, []string{"name"}, &ol, property.Filter{"name": name})
-		if err != nil {
-			err = fmt.Errorf("failed to find %v %v %v", name, vmwareType, err)
-			return
-		}
-		moid = ol.Reference()
-	case "Datastore":
-		ol := mo.Datastore{}
-		err = v.RetrieveWithFilter(ctx, []string{"Datastore"}, []string{"name"}, &ol, property.Filter{"name": name})
-		if err != nil {
-			err = fmt.Errorf("failed to find %v %v %v", name, vmwareType, err)
-			return
-		}
-		moid = ol.Reference()
+	case "HostSystem", "VirtualMachine", "Datastore", "VmwareDistributedVirtualSwitch", "ClusterComputeResource", "ResourcePool", "StoragePod", "DistributedVirtualPortgroup":
+	default:
+		return moid, fmt.Errorf("findOne() unsupported type %v", vmwareType)
+	}
 
-	case "VmwareDistributedVirtualSwitch":
-		ol := mo.VmwareDistributedVirtualSwitch{}
-		err = v.RetrieveWithFilter(ctx, []string{"VmwareDistributedVirtualSwitch"}, []string{"name"}, &ol, property.Filter{"name": name})
+	var refs []types.ManagedObjectReference
+	err = c.retry(ctx, func() error {
+		v, err := c.m.CreateContainerView(ctx, c.c.ServiceContent.RootFolder, []string{vmwareType}, true)
 		if err != nil {
-			err = fmt.Errorf("failed to find %v %v %v", name, vmwareType, err)
-			return
+			return err
 		}
-		moid = ol.Reference()
-
-	default:
-		fmt.Println("findOne() unsupported type")
-		return
+		defer func() { _ = v.Destroy(ctx) }()
+		refs, err = v.Find(ctx, []string{vmwareType}, property.Filter{"name": name})
+		return err
+	})
+	if err != nil {
+		return moid, fmt.Errorf("failed to find %v %v %v", name, vmwareType, err)
 	}
-
-	return
+	if len(refs) == 0 {
+		return moid, fmt.Errorf("failed to find %v %v", name, vmwareType)
+	}
+	c.storeFind(name, vmwareType, refs[0])
+	return refs[0], nil
 }
 
-//VMSummary  stats for a VM
-func (c *Client) VMSummary(name, moid string, lim *LimitsStruct, age time.Duration, txt bool, sensors []string) error {
-	vmSummaryDefault = append(vmSummaryDefault, sensors...)
+// VMSummary  stats for a VM
+func (c *Client) VMSummary(ctx context.Context, name, moid string, lim *LimitsStruct, age time.Duration, txt bool, sensors []string) error {
+	metrics := append(append([]string{}, vmSummaryDefault...), sensors...)
 	start := time.Now()
-	ctx := context.Background()
-	ctx, _ = context.WithTimeout(ctx, 30*time.Second)
+	ictx, cancel := c.inventoryCtx(ctx)
+	defer cancel()
 
 	if c.m == nil {
 		return fmt.Errorf("no manager")
 	}
-	v, err := c.m.CreateContainerView(ctx, c.c.ServiceContent.RootFolder, []string{"VirtualMachine"}, true)
-	if err != nil {
-		return fmt.Errorf("con view 1 %v", err)
-	}
-	defer func() { _ = v.Destroy(ctx) }()
 
 	id := types.ManagedObjectReference{
 		Type: "VirtualMachine", Value: moid,
 	}
 
 	v0 := mo.VirtualMachine{}
+	var err error
 
 	if moid == "" {
-		id, err = c.findOne(name, "VirtualMachine")
+		id, err = c.findOne(ictx, name, "VirtualMachine")
 		if err != nil {
-			return fmt.Errorf("c.findOne %v", err)
+			return PhaseError(ictx, "inventory", fmt.Errorf("c.findOne %v", err))
 		}
 	}
-	err = v.Properties(ctx, id, []string{"name", "summary", "snapshot", "guest", "runtime"}, &v0)
+	err = c.retrieveOne(ictx, id, []string{"name", "summary", "snapshot", "guest", "runtime"}, &v0)
 	if err != nil {
-		return errCheck(name, id, fmt.Errorf("vm v.properties %v", err))
+		return PhaseError(ictx, "inventory", errCheck(name, id, fmt.Errorf("vm v.properties %v", err)))
 	}
 
 	elapsed := time.Since(start)
@@ -244,11 +212,10 @@ func (c *Client) VMSummary(name, moid string, lim *LimitsStruct, age time.Durati
 			return fmt.Errorf("snapshot %v", err)
 		}
 	}
-	pr := newPrtgData(v0.Name)
-	pr.moid = id.Value
-	_ = pr.add(co, ps.SensorChannel{Channel: fmt.Sprintf("Snapshots Older Than %v", age), Unit: "Custom", CustomUnit: "Found", LimitErrorMsg: lim.ErrMsg, LimitMaxError: lim.MaxErr, LimitMaxWarning: lim.MaxWarn, LimitWarningMsg: lim.WarnMsg})
+	pr := c.prtgData(id, v0.Name)
+	_ = pr.addSample(co, ps.SensorChannel{Channel: fmt.Sprintf("Snapshots Older Than %v", age), Unit: "Custom", CustomUnit: "Found", LimitErrorMsg: lim.ErrMsg, LimitMaxError: lim.MaxErr, LimitMaxWarning: lim.MaxWarn, LimitWarningMsg: lim.WarnMsg}, "snapshots older than", "")
 
-	gt := ps.SensorChannel{Channel: "guest tools running", Unit: "Custom", ValueLookup: "prtg.standardlookups.exchangedag.yesno.allstatesok"}
+	gt := ps.SensorChannel{Channel: "guest tools running", Unit: "Custom", ValueLookup: lookupToolsRunning}
 	var gtv int
 	switch v0.Guest.ToolsRunningStatus {
 	case "guestToolsRunning":
@@ -258,11 +225,12 @@ func (c *Client) VMSummary(name, moid string, lim *LimitsStruct, age time.Durati
 
 	}
 	_ = pr.add(gtv, gt)
+	_ = pr.add(toolsStatus(v0.Guest.ToolsStatus), ps.SensorChannel{Channel: "Guest Tools Status", Unit: "Custom", ValueLookup: lookupToolsStatus})
 
 	hs := mo.HostSystem{}
-	err = v.Properties(ctx, v0.Runtime.Host.Reference(), []string{"name"}, &hs)
+	err = c.retrieveOne(ictx, v0.Runtime.Host.Reference(), []string{"name", "parent"}, &hs)
 	if err != nil {
-		return fmt.Errorf("hostsystem properties failure %v", err)
+		return PhaseError(ictx, "inventory", fmt.Errorf("hostsystem properties failure %v", err))
 	}
 
 	for _, v := range v0.Guest.Disk {
@@ -274,12 +242,15 @@ func (c *Client) VMSummary(name, moid string, lim *LimitsStruct, age time.Durati
 		free := v.FreeSpace
 		one := ca / 100
 		perc := free / one
-		_ = pr.add(free, ps.SensorChannel{Channel: "free Bytes " + d, Unit: "BytesDisk", VolumeSize: "KiloByte", ShowChart: "0", ShowTable: "0"})
-		_ = pr.add(perc, ps.SensorChannel{Channel: "free Space (Percent) " + d, Unit: "Percent", LimitMinWarning: "20", LimitMinError: "10", LimitWarningMsg: "Warning Low Space", LimitErrorMsg: "Critical disk space", LimitMode: "1"})
+		_ = pr.addSample(free, ps.SensorChannel{Channel: "free Bytes " + d, Unit: "BytesDisk", VolumeSize: "KiloByte", ShowChart: "0", ShowTable: "0"}, "guest disk free bytes", d)
+		_ = pr.addSample(perc, ps.SensorChannel{Channel: "free Space (Percent) " + d, Unit: "Percent", LimitMinWarning: "20", LimitMinError: "10", LimitWarningMsg: "Warning Low Space", LimitErrorMsg: "Critical disk space", LimitMode: "1"}, "guest disk free percent", d)
 	}
+	pr.host, pr.cluster = hs.Name, c.clusterName(ictx, hs.Parent)
+	pr.power = string(v0.Runtime.PowerState)
+	pr.uptime = time.Duration(v0.Summary.QuickStats.UptimeSeconds) * time.Second
 	if v0.Runtime.PowerState == "poweredOn" {
 		pr.text = "OK running on Host " + hs.Name
-		err = c.Metrics(v0.Reference(), pr, vmSummaryDefault, 20)
+		err = c.Metrics(ctx, v0.Reference(), pr, metrics, 20)
 		if err != nil {
 			return err
 		}
@@ -302,33 +273,32 @@ func errCheck(name string, oid types.ManagedObjectReference, err error) error {
 	return err
 }
 
-//SnapShotsOlderThan tag focused snapshot reporting
-func (c *Client) SnapShotsOlderThan(f property.Filter, tagIds []string, lim *LimitsStruct, age time.Duration, txt bool) (err error) {
+// SnapShotsOlderThan tag focused snapshot reporting
+func (c *Client) SnapShotsOlderThan(ctx context.Context, f property.Filter, tagIds []string, lim *LimitsStruct, age time.Duration, txt bool) (err error) {
 	start := time.Now()
-	ctx := context.Background()
-	ctx, _ = context.WithTimeout(ctx, 30*time.Second)
-
-	m := view.NewManager(c.c)
-
-	v, err := m.CreateContainerView(ctx, c.c.ServiceContent.RootFolder, []string{"VirtualMachine"}, true)
-	if err != nil {
-		return
-	}
-	defer func() { _ = v.Destroy(ctx) }()
+	ctx, cancel := c.inventoryCtx(ctx)
+	defer cancel()
 
 	// retrieve snapshot info
 	var vms []mo.VirtualMachine
-	err = v.RetrieveWithFilter(ctx, []string{"ManagedEntity"}, []string{"snapshot", "name"}, &vms, f)
+	err = c.retry(ctx, func() error {
+		v, err := view.NewManager(c.c).CreateContainerView(ctx, c.c.ServiceContent.RootFolder, []string{"VirtualMachine"}, true)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = v.Destroy(ctx) }()
+		return v.RetrieveWithFilter(ctx, []string{"ManagedEntity"}, []string{"snapshot", "name"}, &vms, f)
+	})
 	if err != nil {
-		return fmt.Errorf("retrieve issue %v", err)
+		return PhaseError(ctx, "inventory", fmt.Errorf("retrieve issue %v", err))
 	}
 
 	// retrieve tags and object associations
-	pr := newPrtgData("snapshots")
+	pr := c.prtgData(types.ManagedObjectReference{Type: "snapshots"}, "snapshots")
 	tm := NewTagMap()
-	err = c.list(tagIds, tm)
+	err = c.list(ctx, tagIds, tm)
 	if err != nil {
-		return err
+		return PhaseError(ctx, "inventory", err)
 	}
 
 	respTime := time.Since(start)
@@ -352,7 +322,7 @@ func (c *Client) SnapShotsOlderThan(f property.Filter, tagIds []string, lim *Lim
 
 			if noTags || tm.check(v.Self.Value, tagIds) {
 				stat := fmt.Sprintf("%v", v.Name)
-				err = pr.add(co, ps.SensorChannel{Channel: stat, Unit: "Custom", CustomUnit: "Found", LimitErrorMsg: lim.ErrMsg, LimitMaxError: lim.MaxErr, LimitMaxWarning: lim.MaxWarn, LimitWarningMsg: lim.WarnMsg})
+				err = pr.addSample(co, ps.SensorChannel{Channel: stat, Unit: "Custom", CustomUnit: "Found", LimitErrorMsg: lim.ErrMsg, LimitMaxError: lim.MaxErr, LimitMaxWarning: lim.MaxWarn, LimitWarningMsg: lim.WarnMsg}, "snapshots older than", v.Name)
 				if err != nil {
 					return
 				}
@@ -367,38 +337,32 @@ func (c *Client) SnapShotsOlderThan(f property.Filter, tagIds []string, lim *Lim
 
 }
 
-//DsSummary stats for a datastore
-func (c *Client) DsSummary(name, moid string, lim *LimitsStruct, js bool) (err error) {
+// DsSummary stats for a datastore
+func (c *Client) DsSummary(ctx context.Context, name, moid string, lim *LimitsStruct, js bool) (err error) {
 
 	start := time.Now()
-	ctx := context.Background()
-	ctx, _ = context.WithTimeout(ctx, 30*time.Second)
-	v, err := c.m.CreateContainerView(ctx, c.c.ServiceContent.RootFolder, []string{"Datastore"}, true)
-	if err != nil {
-		return nil
-	}
+	ictx, cancel := c.inventoryCtx(ctx)
+	defer cancel()
 
-	defer func() { _ = v.Destroy(ctx) }()
 	id := types.ManagedObjectReference{
 		Type:  "Datastore",
 		Value: moid,
 	}
 
 	if moid == "" {
-		id, err = c.findOne(name, id.Type)
+		id, err = c.findOne(ictx, name, id.Type)
 		if err != nil {
-			return fmt.Errorf("c.findOne %v", err)
+			return PhaseError(ictx, "inventory", fmt.Errorf("c.findOne %v", err))
 		}
 
 	}
 
 	ds := mo.Datastore{}
-	err = v.Properties(ctx, id, []string{"name", "summary"}, &ds)
+	err = c.retrieveOne(ictx, id, []string{"name", "summary"}, &ds)
 	if err != nil {
-		return errCheck(name, id, fmt.Errorf("ds v.properties %v", err))
+		return PhaseError(ictx, "inventory", errCheck(name, id, fmt.Errorf("ds v.properties %v", err)))
 	}
-	pr := newPrtgData(ds.Name)
-	pr.moid = id.Value
+	pr := c.prtgData(id, ds.Name)
 	whole := ds.Summary.Capacity
 	free := ds.Summary.FreeSpace
 	p1 := whole / 100
@@ -416,10 +380,10 @@ func (c *Client) DsSummary(name, moid string, lim *LimitsStruct, js bool) (err e
 		mm = "1"
 	}
 
-	_ = pr.add(mm, ps.SensorChannel{Channel: "Maintenance Mode", Unit: "Custom", LimitMaxWarning: "1", ValueLookup: "prtg.standardlookups.boolean.statefalseok"})
+	_ = pr.add(mm, ps.SensorChannel{Channel: "Maintenance Mode", Unit: "Custom", LimitMaxWarning: "1", ValueLookup: lookupMaintenanceMode})
 	_ = pr.add(boolToInt(ds.Summary.Accessible), ps.SensorChannel{Channel: "Accessible", Unit: "Custom", LimitMaxWarning: "1", ValueLookup: "prtg.standardlookups.boolean.statetrueok"})
 
-	err = c.Metrics(ds.Reference(), pr, dsSummaryDefault, 1800)
+	err = c.Metrics(ctx, ds.Reference(), pr, dsSummaryDefault, 1800)
 	if err != nil {
 		return err
 	}
@@ -427,96 +391,82 @@ func (c *Client) DsSummary(name, moid string, lim *LimitsStruct, js bool) (err e
 	return nil
 }
 
-//VdsSummary  stats for a VDS
-func (c *Client) VdsSummary(name, moid string, js bool) (err error) {
+// VdsSummary  stats for a VDS
+func (c *Client) VdsSummary(ctx context.Context, name, moid string, js bool) (err error) {
 	start := time.Now()
 
-	ctx := context.Background()
-	ctx, _ = context.WithTimeout(ctx, 30*time.Second)
-
-	v, err := c.m.CreateContainerView(ctx, c.c.ServiceContent.RootFolder, []string{"VmwareDistributedVirtualSwitch"}, true)
-	if err != nil {
-		return fmt.Errorf("create container %v", err)
-	}
-	defer func() { _ = v.Destroy(ctx) }()
+	ictx, cancel := c.inventoryCtx(ctx)
+	defer cancel()
 
 	id := types.ManagedObjectReference{
 		Type:  "VmwareDistributedVirtualSwitch",
 		Value: moid,
 	}
 	if moid == "" {
-		id, err = c.findOne(name, "VmwareDistributedVirtualSwitch")
+		id, err = c.findOne(ictx, name, "VmwareDistributedVirtualSwitch")
 		if err != nil {
-			return
+			return PhaseError(ictx, "inventory", err)
 		}
 	}
 	vds := mo.VmwareDistributedVirtualSwitch{}
-	err = v.Properties(ctx, id, nil, &vds)
+	err = c.retrieveOne(ictx, id, nil, &vds)
 	if err != nil {
-		return errCheck(name, id, fmt.Errorf("vds v.properties %v", err))
+		return PhaseError(ictx, "inventory", errCheck(name, id, fmt.Errorf("vds v.properties %v", err)))
 	}
 
 	elapsed := time.Since(start)
-	pr := newPrtgData("VdsSummary")
+	pr := c.prtgData(id, vds.Name)
 
-	_ = pr.add(tfl(vds.OverallStatus), ps.SensorChannel{Channel: "Overall Status", Unit: "Custom", CustomUnit: "Custom", ValueLookup: "prtg.standardlookups.Google.Gsa.Health"})
-	_ = pr.add(tfl(vds.ConfigStatus), ps.SensorChannel{Channel: "Config Status", Unit: "Custom", CustomUnit: "Custom", ValueLookup: "prtg.standardlookups.Google.Gsa.Health"})
+	_ = pr.add(managedEntityStatus(vds.OverallStatus), ps.SensorChannel{Channel: "Overall Status", Unit: "Custom", CustomUnit: "Custom", ValueLookup: lookupStatus})
+	_ = pr.add(managedEntityStatus(vds.ConfigStatus), ps.SensorChannel{Channel: "Config Status", Unit: "Custom", CustomUnit: "Custom", ValueLookup: lookupStatus})
 
 	for _, pg := range vds.Portgroup {
 		vpg := mo.DistributedVirtualPortgroup{}
-		err = v.Properties(ctx, pg, nil, &vpg)
+		err = c.retrieveOne(ictx, pg, nil, &vpg)
 		if err != nil {
-			return fmt.Errorf("hs properties %v", err)
+			return PhaseError(ictx, "inventory", fmt.Errorf("hs properties %v", err))
 		}
-		_ = pr.add(tfl(vpg.OverallStatus), ps.SensorChannel{Channel: vpg.Name, Unit: "Custom", CustomUnit: "Custom", ValueLookup: "prtg.standardlookups.Google.Gsa.Health"})
+		_ = pr.addSample(managedEntityStatus(vpg.OverallStatus), ps.SensorChannel{Channel: vpg.Name, Unit: "Custom", CustomUnit: "Custom", ValueLookup: lookupStatus}, "portgroup status", vpg.Name)
 	}
-	_ = c.Metrics(vds.Reference(), pr, vdsSummaryDefault, 20)
+	_ = c.Metrics(ctx, vds.Reference(), pr, vdsSummaryDefault, 20)
 	err = pr.print(elapsed, js)
 
 	return
 }
 
-//HostSummary  stats for a host system
-func (c *Client) HostSummary(name, moid string, js bool) (err error) {
+// HostSummary  stats for a host system
+func (c *Client) HostSummary(ctx context.Context, name, moid string, js bool) (err error) {
 	start := time.Now()
 
-	ctx := context.Background()
-	ctx, _ = context.WithTimeout(ctx, 30*time.Second)
+	ictx, cancel := c.inventoryCtx(ctx)
+	defer cancel()
 
-	v, err := c.m.CreateContainerView(ctx, c.c.ServiceContent.RootFolder, []string{"HostSystem"}, true)
-	if err != nil {
-		return fmt.Errorf("create container %v", err)
-	}
-	defer func() { _ = v.Destroy(ctx) }()
 	id := types.ManagedObjectReference{
 		Type:  "HostSystem",
 		Value: moid,
 	}
 
 	if moid == "" {
-		id, err = c.findOne(name, "HostSystem")
+		id, err = c.findOne(ictx, name, "HostSystem")
 		if err != nil {
-			return
+			return PhaseError(ictx, "inventory", err)
 		}
 	}
 	hs := mo.HostSystem{}
-	err = v.Properties(ctx, id, nil, &hs)
+	err = c.retrieveOne(ictx, id, nil, &hs)
 	if err != nil {
-		return errCheck(name, id, fmt.Errorf("hs v.properties %v", err))
+		return PhaseError(ictx, "inventory", errCheck(name, id, fmt.Errorf("hs v.properties %v", err)))
 	}
 
-	pr := newPrtgData("HostSummary")
+	pr := c.prtgData(id, hs.Name)
+	pr.host, pr.cluster = hs.Name, c.clusterName(ictx, hs.Parent)
+	pr.power = string(hs.Runtime.PowerState)
+	pr.uptime = time.Duration(hs.Summary.QuickStats.Uptime) * time.Second
 
-	ps1 := ps.SensorChannel{Channel: "Power state", Unit: "Custom", VolumeSize: "Custom", ValueLookup: "prtg.standardlookups.Google.Gsa.Health", LimitWarningMsg: "Host was put to sleep", LimitErrorMsg: "Host in unknown state, please investigate"}
-	switch hs.Runtime.PowerState {
-	case "poweredOn":
-		_ = pr.add(0, ps1)
-	case "poweredOff", "standby":
-		_ = pr.add(1, ps1)
-		_ = pr.print(time.Since(start), false)
-		return
-	default:
-		_ = pr.add(2, ps1)
+	ps1 := ps.SensorChannel{Channel: "Power state", Unit: "Custom", VolumeSize: "Custom", ValueLookup: lookupPowerState, LimitWarningMsg: "Host was put to sleep", LimitErrorMsg: "Host in unknown state, please investigate"}
+	_ = pr.add(powerState(string(hs.Runtime.PowerState)), ps1)
+	_ = pr.add(connectionState(hs.Runtime.ConnectionState), ps.SensorChannel{Channel: "Connection State", Unit: "Custom", VolumeSize: "Custom", ValueLookup: lookupConnectionState})
+	if hs.Runtime.PowerState != "poweredOn" {
 		_ = pr.print(time.Since(start), false)
 		return
 	}
@@ -540,22 +490,26 @@ func (c *Client) HostSummary(name, moid string, js bool) (err error) {
 	_ = pr.add(freeCPU, ps.SensorChannel{Channel: "CPU Free MHz", Unit: "Custom", VolumeSize: "One", CustomUnit: "MHz"})
 	_ = pr.add(totalCPU, ps.SensorChannel{Channel: "CPU Capacity MHz", Unit: "Custom", VolumeSize: "One", CustomUnit: "MHz"})
 
-	_ = pr.add(boolToInt(hs.Runtime.InMaintenanceMode), ps.SensorChannel{Channel: "Maintenance Mode", Unit: "Custom", VolumeSize: "Custom", ValueLookup: "prtg.standardlookups.boolean.statefalseok"})
+	_ = pr.add(boolToInt(hs.Runtime.InMaintenanceMode), ps.SensorChannel{Channel: "Maintenance Mode", Unit: "Custom", VolumeSize: "Custom", ValueLookup: lookupMaintenanceMode})
 	_ = pr.add(triggeredAlarms(hs.TriggeredAlarmState), ps.SensorChannel{Channel: "Triggered Alarms", Unit: "Count", LimitMaxWarning: "1", LimitWarningMsg: "triggered alarms present"})
 
 	pr.text = fmt.Sprint(hs.Runtime.PowerState)
-	diskPathDetails := hs.Config.StorageDevice.MultipathInfo
 	var triggered bool
-	for _, lun := range diskPathDetails.Lun {
-		for _, path := range lun.Path {
-			if !*path.IsWorkingPath {
-				triggered = true
-				pr.text = "Path failure " + path.Name
+	if hs.Config != nil && hs.Config.StorageDevice != nil && hs.Config.StorageDevice.MultipathInfo != nil {
+		for _, lun := range hs.Config.StorageDevice.MultipathInfo.Lun {
+			for _, path := range lun.Path {
+				if path.IsWorkingPath != nil && !*path.IsWorkingPath {
+					triggered = true
+					pr.failures = append(pr.failures, path.Name)
+				}
 			}
 		}
 	}
+	if triggered {
+		pr.text = "Path failure " + strings.Join(pr.failures, ", ")
+	}
 	_ = pr.add(boolToInt(triggered), ps.SensorChannel{Channel: "storage_path_error", Unit: "Custom", VolumeSize: "Custom", ValueLookup: "prtg.standardlookups.boolean.statefalseok", LimitErrorMsg: "check storage paths"})
-	err = c.Metrics(id, pr, hsSummaryDefault, 20)
+	err = c.Metrics(ctx, id, pr, hsSummaryDefault, 20)
 	if err != nil {
 		return
 	}
@@ -563,7 +517,14 @@ func (c *Client) HostSummary(name, moid string, js bool) (err error) {
 	return
 }
 
+// maxQueryCap is the most metrics we ask for in one query whatever the server allows
+const maxQueryCap = 10000
+
 func (c *Client) getMaxQueryMetrics(ctx context.Context) (int, error) {
+	// the limit is a vpxd setting, ESXi hosts don't have one
+	if !c.c.IsVC() || c.c.ServiceContent.Setting == nil {
+		return maxQueryCap, nil
+	}
 
 	om := object.NewOptionManager(c.c, *c.c.ServiceContent.Setting)
 	res, err := om.Query(ctx, "config.vpxd.stats.maxQueryMetrics")
@@ -577,7 +538,7 @@ func (c *Client) getMaxQueryMetrics(ctx context.Context) (int, error) {
 
 				if v == -1 {
 					// Whatever the server says, we never ask for more metrics than this.
-					return 10000, nil
+					return maxQueryCap, nil
 				}
 				return v, nil
 			}
@@ -602,16 +563,33 @@ func (c *Client) getMaxQueryMetrics(ctx context.Context) (int, error) {
 	return 256, nil
 }
 
-//Metrics returns metrics for a given object
-func (c *Client) Metrics(mor types.ManagedObjectReference, pr *prtgData, str []string, interval int32) (err error) {
+// historicalPerfInterval is the shortest historical interval, used for objects without real-time stats
+const historicalPerfInterval = 300
 
-	ctx := context.Background()
-	ctx, _ = context.WithTimeout(ctx, 30*time.Second)
+// perfInterval picks the sampling interval to query, objects without real-time stats are skipped.
+// ESXi hosts only keep real-time stats, so any other interval is replaced by the host refresh rate
+func perfInterval(psum *types.PerfProviderSummary, interval int32, isVC bool) (int32, bool) {
+	if !psum.CurrentSupported {
+		return 0, false
+	}
+	if !isVC && psum.RefreshRate > 0 {
+		return psum.RefreshRate, true
+	}
+	return interval, true
+}
 
-	perfManager := performance.NewManager(c.c)
+// Metrics returns metrics for a given object
+func (c *Client) Metrics(ctx context.Context, mor types.ManagedObjectReference, pr *prtgData, str []string, interval int32) (err error) {
+	ctx, cancel := c.metricsCtx(ctx)
+	defer cancel()
+	defer func() { err = PhaseError(ctx, "metrics", err) }()
 
 	// Retrieve counters
-	counters, err := perfManager.CounterInfoByName(ctx)
+	var counters map[string]*types.PerfCounterInfo
+	err = c.retry(ctx, func() (err error) {
+		counters, err = c.perfManager().CounterInfoByName(ctx)
+		return
+	})
 	if err != nil {
 		return fmt.Errorf("perfmanager %v", err)
 	}
@@ -622,28 +600,45 @@ func (c *Client) Metrics(mor types.ManagedObjectReference, pr *prtgData, str []s
 		names = append(names, name)
 	}
 
-	// Check PerfQuerySpec
-	spec := types.PerfQuerySpec{
-		MaxSample:  1,
-		MetricId:   []types.PerfMetricId{},
-		IntervalId: interval,
-	}
-
-	maxQuery, err := c.getMaxQueryMetrics(ctx)
+	var maxQuery int
+	err = c.retry(ctx, func() (err error) {
+		maxQuery, err = c.maxQueryMetrics(ctx)
+		return
+	})
 	if err != nil {
 		return fmt.Errorf("getMaxQueryMetrics %v", err)
 	}
-	psum, err := perfManager.ProviderSummary(ctx, mor)
+	var psum *types.PerfProviderSummary
+	err = c.retry(ctx, func() (err error) {
+		psum, err = c.perfManager().ProviderSummary(ctx, mor)
+		return
+	})
 	if err != nil {
 		return fmt.Errorf("object not found %v", mor)
 	}
-	if !psum.CurrentSupported {
-		fmt.Printf("%v performance metrics not available\n", mor)
+	interval, ok := perfInterval(psum, interval, c.c.IsVC())
+	if !ok && (mor.Type == "ClusterComputeResource" || mor.Type == "ResourcePool") && psum.SummarySupported {
+		// clusters and pools only keep historical stats, the latest rollup is the most recent value
+		interval, ok = historicalPerfInterval, true
+	}
+	if !ok {
+		// object has no usable performance provider, sensor still reports its other channels
 		return
 	}
 
+	// Check PerfQuerySpec
+	spec := types.PerfQuerySpec{
+		MaxSample:  1,
+		MetricId:   []types.PerfMetricId{},
+		IntervalId: interval,
+	}
+
 	// Query metrics
-	sample, err := perfManager.SampleByName(ctx, spec, names, []types.ManagedObjectReference{mor})
+	var sample []types.BasePerfEntityMetricBase
+	err = c.retry(ctx, func() (err error) {
+		sample, err = c.perfManager().SampleByName(ctx, spec, names, []types.ManagedObjectReference{mor})
+		return
+	})
 	if (err != nil) || len(sample) == 0 {
 		return fmt.Errorf("could not find sample data for %v, err: %v", mor, err)
 	}
@@ -653,7 +648,7 @@ func (c *Client) Metrics(mor types.ManagedObjectReference, pr *prtgData, str []s
 		return fmt.Errorf("query metrics level too low, needed %v  max setting %v", len(sample), maxQuery)
 	}
 
-	result, err := perfManager.ToMetricSeries(ctx, sample)
+	result, err := c.perfManager().ToMetricSeries(ctx, sample)
 	if err != nil {
 		return fmt.Errorf("metrics %v", err)
 	}
@@ -672,7 +667,7 @@ func (c *Client) Metrics(mor types.ManagedObjectReference, pr *prtgData, str []s
 
 		var hide bool
 		counter := counters[v.Name]
-		instance := v.Instance
+		family, instance := v.Name, v.Instance
 		if inStringSlice(v.Name, str) {
 			if instance != "" {
 				// special handling of metric names using instance data
@@ -701,10 +696,10 @@ func (c *Client) Metrics(mor types.ManagedObjectReference, pr *prtgData, str []s
 
 			units := counter.UnitInfo.GetElementDescription().Label
 
-			// special handling for int's
-			fixedPointFloat := float64(v.Value[0]) / 100
-			if strings.Contains(v.Name, "power") || strings.Contains(v.Name, "sys.uptime") {
-				fixedPointFloat = float64(v.Value[0])
+			// percentages are reported as fixed point with two decimals, other units are absolute values
+			fixedPointFloat := float64(v.Value[0])
+			if counter.UnitInfo.GetElementDescription().Key == "percent" {
+				fixedPointFloat /= 100
 			}
 
 			// get PRTG version of vmware metric, eg type % == Percent
@@ -712,10 +707,10 @@ func (c *Client) Metrics(mor types.ManagedObjectReference, pr *prtgData, str []s
 
 			// allow hiding of verbose channels
 			if !hide {
-				_ = pr.add(fixedPointFloat, ps.SensorChannel{Channel: v.Name, Unit: u, VolumeSize: s, CustomUnit: cu}) //, DecimalMode: decMode})
+				_ = pr.addSample(fixedPointFloat, ps.SensorChannel{Channel: v.Name, Unit: u, VolumeSize: s, CustomUnit: cu}, family, instance) //, DecimalMode: decMode})
 
 			} else {
-				_ = pr.add(fixedPointFloat, ps.SensorChannel{Channel: v.Name, Unit: u, VolumeSize: s, CustomUnit: cu, ShowChart: "0", ShowTable: "0"})
+				_ = pr.addSample(fixedPointFloat, ps.SensorChannel{Channel: v.Name, Unit: u, VolumeSize: s, CustomUnit: cu, ShowChart: "0", ShowTable: "0"}, family, instance)
 
 			}
 		}
@@ -903,20 +898,3 @@ func boolToInt(b bool) int {
 	}
 	return 0
 }
-
-func tfl(ic interface{}) int {
-	c := fmt.Sprintf("%v", ic)
-	var i int
-	switch c {
-	case "green":
-
-	case "yellow":
-		i = 1
-	case "red":
-		i = 2
-	default:
-		fmt.Println("tfl", ic)
-		i = 9
-	}
-	return i
-}
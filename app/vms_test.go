@@ -17,6 +17,7 @@
 package app
 
 import (
+	"context"
 	"github.com/vmware/govmomi/property"
 	"github.com/vmware/govmomi/vim25/types"
 	"net/url"
@@ -58,14 +59,14 @@ func TestClient_Metrics(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			c, err := NewClient(u, user, passwd, true)
+			c, err := NewClient(context.Background(), u, user, passwd, true, TLSOptions{Insecure: true})
 			if err != nil {
 				t.Fatalf("failed %v", err)
 			}
 			defer func() { _ = c.Logout() }()
 
 			pr := newPrtgData("testing")
-			err = c.Metrics(tt.prop, pr, tt.metrics, tt.interval)
+			err = c.Metrics(context.Background(), tt.prop, pr, tt.metrics, tt.interval)
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -92,13 +93,13 @@ func TestClient_vmSummary(t *testing.T) {
 	//	debug = true
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			c, err := NewClient(u, tt.args.usr, tt.args.pw, true)
+			c, err := NewClient(context.Background(), u, tt.args.usr, tt.args.pw, true, TLSOptions{Insecure: true})
 			if err != nil {
 				t.Fatalf("%+v", err)
 			}
 			defer func() { _ = c.Logout() }()
 			lim := &LimitsStruct{}
-			err = c.VMSummary(tt.args.searchName, tt.args.searchMoid, lim, time.Hour, tt.args.txt, []string{"cpu.ready.summation"})
+			err = c.VMSummary(context.Background(), tt.args.searchName, tt.args.searchMoid, lim, time.Hour, tt.args.txt, []string{"cpu.ready.summation"})
 			if (err != nil) && !tt.wantErr {
 				t.Fatal(err)
 			}
@@ -120,13 +121,13 @@ func TestClient_DsSummarys(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			c, err := NewClient(u, user, passwd, true)
+			c, err := NewClient(context.Background(), u, user, passwd, true, TLSOptions{Insecure: true})
 			if err != nil {
 				t.Fatalf("failed %v", err)
 			}
 			defer func() { _ = c.Logout() }()
 
-			err = c.DsSummary(tt.na, tt.moid, &LimitsStruct{}, false)
+			err = c.DsSummary(context.Background(), tt.na, tt.moid, &LimitsStruct{}, false)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("DsMetrics() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -148,13 +149,13 @@ func TestClient_HostSummary(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			c, err := NewClient(u, user, passwd, true)
+			c, err := NewClient(context.Background(), u, user, passwd, true, TLSOptions{Insecure: true})
 			if err != nil {
 				t.Fatalf("failed %v", err)
 			}
 			defer func() { _ = c.Logout() }()
 
-			err = c.HostSummary(tt.na, tt.moid, true)
+			err = c.HostSummary(context.Background(), tt.na, tt.moid, true)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("hostsummary() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -180,7 +181,7 @@ func TestClient_VdsSummary(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			c, err := NewClient(u, user, passwd, true)
+			c, err := NewClient(context.Background(), u, user, passwd, true, TLSOptions{Insecure: true})
 			if err != nil {
 				t.Fatalf("failed %v", err)
 			}
@@ -188,7 +189,7 @@ func TestClient_VdsSummary(t *testing.T) {
 
 			pr := newPrtgData("testing")
 
-			err = c.VdsSummary(tt.args.searchName, tt.args.searchMoid, tt.args.txt)
+			err = c.VdsSummary(context.Background(), tt.args.searchName, tt.args.searchMoid, tt.args.txt)
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -453,7 +454,7 @@ func TestSnapShotsOlder(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			c, err := NewClient(tt.ur, tt.args.usr, tt.args.pw, true)
+			c, err := NewClient(context.Background(), tt.ur, tt.args.usr, tt.args.pw, true, TLSOptions{Insecure: true})
 			if err != nil {
 				t.Fatalf("%+v", err)
 			}
@@ -462,7 +463,7 @@ func TestSnapShotsOlder(t *testing.T) {
 			f := property.Filter{tt.args.searchType: "*" + tt.args.searchItem}
 			lim := &LimitsStruct{}
 
-			err = c.SnapShotsOlderThan(f, tt.args.tag, lim, time.Second, tt.args.txt)
+			err = c.SnapShotsOlderThan(context.Background(), f, tt.args.tag, lim, time.Second, tt.args.txt)
 			if (err != nil) && !tt.wantErr {
 				t.Errorf("failed %v", err)
 			}
@@ -493,7 +494,7 @@ func Benchmark_SnapShotsOlder(b *testing.B) {
 		wg := sync.WaitGroup{}
 		for n := 0; n < 1000; n++ {
 			wg.Add(1)
-			c, err := NewClient(tt.ur, tt.args.usr, tt.args.pw, true)
+			c, err := NewClient(context.Background(), tt.ur, tt.args.usr, tt.args.pw, true, TLSOptions{Insecure: true})
 			if err != nil {
 				b.Fatalf("%+v", err)
 			}
@@ -502,7 +503,7 @@ func Benchmark_SnapShotsOlder(b *testing.B) {
 
 			go func() {
 				defer wg.Done()
-				err = c.SnapShotsOlderThan(f, tt.args.tag, lim, time.Second, tt.args.txt)
+				err = c.SnapShotsOlderThan(context.Background(), f, tt.args.tag, lim, time.Second, tt.args.txt)
 				if (err != nil) && !tt.wantErr {
 					b.Errorf("failed %v", err)
 				}
@@ -512,3 +513,27 @@ func Benchmark_SnapShotsOlder(b *testing.B) {
 		wg.Wait()
 	}
 }
+
+func TestPerfInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		psum     types.PerfProviderSummary
+		interval int32
+		isVC     bool
+		want     int32
+		wantOk   bool
+	}{
+		{"vcenter real-time", types.PerfProviderSummary{CurrentSupported: true, RefreshRate: 20}, 20, true, 20, true},
+		{"vcenter historical", types.PerfProviderSummary{CurrentSupported: true, RefreshRate: 20}, 1800, true, 1800, true},
+		{"esxi historical", types.PerfProviderSummary{CurrentSupported: true, RefreshRate: 20}, 1800, false, 20, true},
+		{"no real-time stats", types.PerfProviderSummary{SummarySupported: true, RefreshRate: -1}, 1800, true, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := perfInterval(&tt.psum, tt.interval, tt.isVC)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("perfInterval() = %v %v, want %v %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+/*Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cmd
+
+import (
+	"fmt"
+	"github.com/mutl3y/prtgvmware/app"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "inspect and purge cached sessions",
+	Long: `manage the sessions cached between sensor runs
+
+sessions are encrypted with the password used to create them, supply it with -p to check
+whether a session is still valid or to log it out
+`,
+}
+
+var cacheListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list cached sessions",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sessions, pw, tlsOpts, err := cachedSessions(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		ctx, cancel := commandCtx(cmd.Flags())
+		defer cancel()
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "HOST\tUSER\tAGE\tVALID")
+		for _, s := range sessions {
+			valid := "unknown, password required"
+			if pw != "" {
+				valid = "yes"
+				if err := s.Verify(ctx, pw, tlsOpts); err != nil {
+					valid = fmt.Sprintf("no, %v", err)
+				}
+			}
+			_, _ = fmt.Fprintf(tw, "%v\t%v\t%v\t%v\n", s.Host, s.User, time.Since(s.LastUsed).Truncate(time.Second), valid)
+		}
+		return tw.Flush()
+	},
+}
+
+var cacheVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "check cached sessions are still accepted by vcenter",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sessions, pw, tlsOpts, err := cachedSessions(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		if pw == "" {
+			return fmt.Errorf("password required to verify sessions")
+		}
+		ctx, cancel := commandCtx(cmd.Flags())
+		defer cancel()
+		var failed int
+		for _, s := range sessions {
+			if err := s.Verify(ctx, pw, tlsOpts); err != nil {
+				failed++
+				fmt.Printf("FAILED %v %v %v\n", s.Host, s.User, err)
+				continue
+			}
+			fmt.Printf("OK     %v %v\n", s.Host, s.User)
+		}
+		if failed > 0 {
+			return fmt.Errorf("%v of %v sessions failed verification", failed, len(sessions))
+		}
+		return nil
+	},
+}
+
+var cachePurgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "delete cached sessions without logging out",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sessions, _, _, err := cachedSessions(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		for _, s := range sessions {
+			if err := s.Purge(); err != nil {
+				return fmt.Errorf("purge %v %v %v", s.Host, s.User, err)
+			}
+			fmt.Printf("purged %v %v\n", s.Host, s.User)
+		}
+		return nil
+	},
+}
+
+var cacheLogoutCmd = &cobra.Command{
+	Use:   "logout-all",
+	Short: "log out cached sessions and delete them",
+	Long: `logs each cached session out of vcenter before deleting it
+
+sessions that can't be decrypted with the supplied password are deleted without logging out`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sessions, pw, tlsOpts, err := cachedSessions(cmd.Flags())
+		if err != nil {
+			return err
+		}
+		ctx, cancel := commandCtx(cmd.Flags())
+		defer cancel()
+		for _, s := range sessions {
+			if err := s.Logout(ctx, pw, tlsOpts); err != nil {
+				fmt.Printf("removed %v %v, logout failed %v\n", s.Host, s.User, err)
+				continue
+			}
+			fmt.Printf("logged out %v %v\n", s.Host, s.User)
+		}
+		return nil
+	},
+}
+
+func cachedSessions(flags *pflag.FlagSet) (sessions []app.CachedSession, pw string, tlsOpts app.TLSOptions, err error) {
+	host, err := flags.GetString("host")
+	if err != nil {
+		return
+	}
+	pw, err = flags.GetString("password")
+	if err != nil {
+		return
+	}
+	tlsOpts, err = tlsOptions(flags)
+	if err != nil {
+		return
+	}
+	sessions, err = app.CachedSessions(host)
+	return
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheListCmd, cacheVerifyCmd, cachePurgeCmd, cacheLogoutCmd)
+	cacheCmd.PersistentFlags().String("host", "", "limit to sessions for this vcenter host")
+}
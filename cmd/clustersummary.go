@@ -0,0 +1,69 @@
+/*Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
+)
+
+// clusterSummaryCmd represents the clusterSummary command
+var clusterSummaryCmd = &cobra.Command{
+	Use:   "clusterSummary",
+	Short: "summary for a single cluster",
+	Long: `
+queries cluster HA and DRS settings, capacity, host states & metrics and outputs in PRTG format
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runSensor(cmd, clusterSummary)
+	},
+}
+
+func clusterSummary(ctx context.Context, flags *pflag.FlagSet, w io.Writer) error {
+	c, err := login(ctx, flags)
+	if err != nil {
+		return err
+	}
+	c.SetOutput(w)
+	oid, err := flags.GetString("oid")
+	if err != nil {
+		return err
+	}
+
+	name, err := flags.GetString("name")
+	if err != nil {
+		return err
+	}
+	if name == "" && oid == "" {
+		return fmt.Errorf("you need to provide a name or managed object id")
+	}
+	js, err := flags.GetBool("json")
+	if err != nil {
+		return err
+	}
+	err = c.ClusterSummary(ctx, name, oid, js)
+	if !c.Cached {
+		_ = c.Logout()
+	}
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(clusterSummaryCmd)
+	registerSensor(clusterSummaryCmd, clusterSummary)
+}
@@ -1,5 +1,4 @@
-/*
- * Copyright © 2019.  mutl3y
+/*Copyright © 2019.  mutl3y
  *
  * Licensed under the Apache License, Version 2.0 (the "License");
  * you may not use this file except in compliance with the License.
@@ -16,9 +15,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
-	"github.com/mutl3y/prtgvmware/app"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
 )
 
 // dssummaryCmd represents the dssummary command
@@ -29,56 +30,53 @@ var dssummaryCmd = &cobra.Command{
 queries datastore summary metrics and outputs in PRTG format
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		flags := cmd.Flags()
-		c, err := login(flags)
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
-		oid, err := flags.GetString("oid")
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
+		runSensor(cmd, dsSummary)
+	},
+}
 
-		lim, err := limitStruct(flags)
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
-		if lim.MinWarn < "20" {
-			lim.MinWarn = "20"
-		}
-		if lim.MinErr < "10" {
-			lim.MinErr = "20"
-		}
+func dsSummary(ctx context.Context, flags *pflag.FlagSet, w io.Writer) error {
+	c, err := login(ctx, flags)
+	if err != nil {
+		return err
+	}
+	c.SetOutput(w)
+	oid, err := flags.GetString("oid")
+	if err != nil {
+		return err
+	}
 
-		name, err := flags.GetString("name")
-		if err != nil {
-			app.SensorWarn(err, true)
-		}
-		if name == "" && oid == "" {
-			app.SensorWarn(fmt.Errorf("you need to provide a name or managed object id"), true)
-			return
-		}
-		js, err := flags.GetBool("json")
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
-		err = c.DsSummary(name, oid, &lim, js)
-		if err != nil {
-			app.SensorWarn(err, true)
+	lim, err := limitStruct(flags)
+	if err != nil {
+		return err
+	}
+	if lim.MinWarn < "20" {
+		lim.MinWarn = "20"
+	}
+	if lim.MinErr < "10" {
+		lim.MinErr = "20"
+	}
 
-		}
-		if !c.Cached {
-			_ = c.Logout()
-		}
-	},
+	name, err := flags.GetString("name")
+	if err != nil {
+		return err
+	}
+	if name == "" && oid == "" {
+		return fmt.Errorf("you need to provide a name or managed object id")
+	}
+	js, err := flags.GetBool("json")
+	if err != nil {
+		return err
+	}
+	err = c.DsSummary(ctx, name, oid, &lim, js)
+	if !c.Cached {
+		_ = c.Logout()
+	}
+	return err
 }
 
 func init() {
 	rootCmd.AddCommand(dssummaryCmd)
+	registerSensor(dssummaryCmd, dsSummary)
 	dssummaryCmd.Flags().BoolP("json", "j", false, "pretty print json version of vmware data")
 
 }
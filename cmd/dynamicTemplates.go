@@ -23,21 +23,27 @@ import (
 var dynamicTemplatesCmd = &cobra.Command{
 	Use:   "dynamicTemplates",
 	Short: "generate prtg template for autodiscovery",
-	Long: `use this to support autodiscovery using VMware tags
+	Long: `use this to support autodiscovery using VMware tags, or --names and --folders on standalone ESXi hosts
 
 run this regually via cron or task scheduler and copy template to devicetemplates folder for use by autodiscovery
+
+--push creates HTTP Push Data Advanced sensors instead of EXE/Script sensors and writes their tokens
+to the given file for use by the push command, tokens of objects already in the file are kept
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 
 		flags := cmd.Flags()
-		c, err := login(flags)
+		ctx, cancel := commandCtx(flags)
+		defer cancel()
+		c, err := login(ctx, flags)
 		if err != nil {
 			app.SensorWarn(err, true)
 			return
 		}
-		tags, err := flags.GetStringSlice("tags")
+		sel, err := selection(flags)
 		if err != nil {
 			app.SensorWarn(err, true)
+			return
 		}
 		snapAge, err := flags.GetDuration("snapAge")
 		if err != nil {
@@ -47,8 +53,34 @@ run this regually via cron or task scheduler and copy template to devicetemplate
 		if err != nil {
 			app.SensorWarn(err, true)
 		}
+		profile, err := flags.GetString("profile")
+		if err != nil {
+			app.SensorWarn(err, true)
+		}
 
-		err = c.DynTemplate(tags, snapAge, tplate)
+		tokens, err := flags.GetString("push")
+		if err != nil {
+			app.SensorWarn(err, true)
+		}
+		if tokens == "" {
+			err = c.DynTemplate(ctx, sel, snapAge, tplate, profile)
+			if err != nil {
+				app.SensorWarn(err, true)
+			}
+			return
+		}
+
+		t, err := pushTokens(flags, tokens)
+		if err != nil {
+			app.SensorWarn(err, true)
+			return
+		}
+		err = c.PushTemplate(ctx, sel, snapAge, tplate, profile, t)
+		if err != nil {
+			app.SensorWarn(err, true)
+			return
+		}
+		err = t.Save(tokens)
 		if err != nil {
 			app.SensorWarn(err, true)
 		}
@@ -59,5 +91,7 @@ func init() {
 
 	rootCmd.AddCommand(dynamicTemplatesCmd)
 	dynamicTemplatesCmd.Flags().StringP("template", "f", "prtgvmware", "filename to save template as, adds .odt, only needed if using multiple sensors")
+	dynamicTemplatesCmd.Flags().String("push", "", "create push sensors and save their tokens to this file, I.E. pushtokens.yml")
+	dynamicTemplatesCmd.Flags().String("pushUrl", "", "probe push endpoint saved in the token file, I.E. http://probe:5050")
 
 }
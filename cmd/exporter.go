@@ -0,0 +1,156 @@
+/*Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/mutl3y/prtgvmware/app"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// exporterCmd represents the exporter command
+var exporterCmd = &cobra.Command{
+	Use:   "exporter",
+	Short: "serve summaries of the selected objects as prometheus metrics",
+	Long: `collects the summary of every host, datastore, distributed switch and vm selected by
+--tags, --names or --folders and serves them on /metrics in the prometheus text format
+
+metric names are vmware_<type>_<counter or summary value>, I.E vmware_vm_cpu_usage_average,
+labelled with vcenter, type, moid, name and instance
+
+by default vcenter is queried on every scrape, use --interval to collect in the background
+and answer scrapes from the latest collection when the inventory takes longer than the
+prometheus scrape timeout
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flags := cmd.Flags()
+		listen, err := flags.GetString("listen")
+		if err != nil {
+			return err
+		}
+		interval, err := flags.GetDuration("interval")
+		if err != nil {
+			return err
+		}
+		noCache, err := flags.GetBool("cachedCreds")
+		if err != nil {
+			return err
+		}
+		e, err := exporter(flags)
+		if err != nil {
+			return err
+		}
+
+		pool = app.NewPool(!noCache)
+		defer pool.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		if interval > 0 {
+			go e.Run(ctx, interval)
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", e)
+		srv := &http.Server{Addr: listen, Handler: mux}
+
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sig
+			cancel()
+			sctx, scancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer scancel()
+			_ = srv.Shutdown(sctx)
+		}()
+
+		log.Printf("exporter listening on %v", listen)
+		err = srv.ListenAndServe()
+		if err != http.ErrServerClosed {
+			return err
+		}
+		log.Printf("exporter stopped")
+		return nil
+	},
+}
+
+// exporter builds an exporter for the vcenter and objects selected by flags
+func exporter(flags *pflag.FlagSet) (*app.Exporter, error) {
+	sel, err := selection(flags)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := collectOptions(flags)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := flags.GetDuration("timeout")
+	if err != nil {
+		return nil, err
+	}
+	urls, err := flags.GetString("url")
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(urls)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid --url %v", urls)
+	}
+
+	return &app.Exporter{
+		Login: func(ctx context.Context) (app.Client, error) {
+			return login(ctx, flags)
+		},
+		VCenter:   u.Hostname(),
+		Selection: sel,
+		Options:   opts,
+		Timeout:   timeout,
+	}, nil
+}
+
+// collectOptions are the summary settings applied to every collected object
+func collectOptions(flags *pflag.FlagSet) (opts app.CollectOptions, err error) {
+	opts.SnapAge, err = flags.GetDuration("snapAge")
+	if err != nil {
+		return
+	}
+	opts.VMMetrics, err = flags.GetStringSlice("vmMetrics")
+	if err != nil {
+		return
+	}
+	opts.Workers, err = flags.GetInt("workers")
+	if err != nil {
+		return
+	}
+	opts.Limits, err = limitStruct(flags)
+	return
+}
+
+func init() {
+	rootCmd.AddCommand(exporterCmd)
+	exporterCmd.Flags().String("listen", ":9272", "address to serve /metrics on")
+	exporterCmd.Flags().Duration("interval", 0, "collect in the background this often, 0 collects on every scrape")
+	exporterCmd.Flags().Int("workers", 4, "objects collected at once")
+	exporterCmd.Flags().StringSlice("vmMetrics", []string{}, "include additional vm metrics, I.E. cpu.ready.summation")
+}
@@ -0,0 +1,100 @@
+/*Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/mutl3y/prtgvmware/app"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
+	"path/filepath"
+)
+
+// hostComplianceCmd represents the hostCompliance command
+var hostComplianceCmd = &cobra.Command{
+	Use:   "hostCompliance",
+	Short: "checks a single host against a baseline file",
+	Long: `
+checks lockdown mode, services, ntp, syslog, advanced settings & certificate validity of a host against a yaml baseline
+and outputs a channel per rule and the percentage of rules passed in PRTG format
+
+lockdown: normal
+services:
+  TSM-SSH: false
+  TSM: false
+ntp: true
+syslog: true
+settings:
+  UserVars.SuppressShellWarning: "0"
+certificateDays: 30
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// a running collector resolves relative paths against its own folder
+		if fn, err := cmd.Flags().GetString("baseline"); err == nil && fn != "" {
+			if abs, err := filepath.Abs(fn); err == nil {
+				_ = cmd.Flags().Set("baseline", abs)
+			}
+		}
+		runSensor(cmd, hostCompliance)
+	},
+}
+
+func hostCompliance(ctx context.Context, flags *pflag.FlagSet, w io.Writer) error {
+	fn, err := flags.GetString("baseline")
+	if err != nil {
+		return err
+	}
+	if fn == "" {
+		return fmt.Errorf("you need to provide a --baseline file")
+	}
+	b, err := app.LoadBaseline(fn)
+	if err != nil {
+		return err
+	}
+	c, err := login(ctx, flags)
+	if err != nil {
+		return err
+	}
+	c.SetOutput(w)
+	oid, err := flags.GetString("oid")
+	if err != nil {
+		return err
+	}
+
+	name, err := flags.GetString("name")
+	if err != nil {
+		return err
+	}
+	if name == "" && oid == "" {
+		return fmt.Errorf("you need to provide a name or managed object id")
+	}
+	js, err := flags.GetBool("json")
+	if err != nil {
+		return err
+	}
+	err = c.HostCompliance(ctx, name, oid, b, js)
+	if !c.Cached {
+		_ = c.Logout()
+	}
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(hostComplianceCmd)
+	registerSensor(hostComplianceCmd, hostCompliance)
+	hostComplianceCmd.Flags().String("baseline", "", "yaml file with the rules hosts are checked against")
+}
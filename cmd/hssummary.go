@@ -15,10 +15,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
-	"github.com/mutl3y/prtgvmware/app"
-
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
 )
 
 // hssummaryCmd represents the hssummary command
@@ -29,42 +30,40 @@ var hssummaryCmd = &cobra.Command{
 queries host summary & metrics and outputs in PRTG format
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		flags := cmd.Flags()
-		c, err := login(flags)
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
-		oid, err := flags.GetString("oid")
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
+		runSensor(cmd, hsSummary)
+	},
+}
 
-		name, err := flags.GetString("name")
-		if err != nil {
-			app.SensorWarn(err, true)
-		}
-		if name == "" && oid == "" {
-			app.SensorWarn(fmt.Errorf("you need to provide a name or managed object id"), true)
-			return
-		}
-		js, err := flags.GetBool("json")
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
-		err = c.HostSummary(name, oid, js)
-		if err != nil {
-			app.SensorWarn(err, true)
+func hsSummary(ctx context.Context, flags *pflag.FlagSet, w io.Writer) error {
+	c, err := login(ctx, flags)
+	if err != nil {
+		return err
+	}
+	c.SetOutput(w)
+	oid, err := flags.GetString("oid")
+	if err != nil {
+		return err
+	}
 
-		}
-		if !c.Cached {
-			_ = c.Logout()
-		}
-	},
+	name, err := flags.GetString("name")
+	if err != nil {
+		return err
+	}
+	if name == "" && oid == "" {
+		return fmt.Errorf("you need to provide a name or managed object id")
+	}
+	js, err := flags.GetBool("json")
+	if err != nil {
+		return err
+	}
+	err = c.HostSummary(ctx, name, oid, js)
+	if !c.Cached {
+		_ = c.Logout()
+	}
+	return err
 }
 
 func init() {
 	rootCmd.AddCommand(hssummaryCmd)
+	registerSensor(hssummaryCmd, hsSummary)
 }
@@ -0,0 +1,50 @@
+/*Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cmd
+
+import (
+	"fmt"
+	"github.com/mutl3y/prtgvmware/app"
+	"github.com/spf13/cobra"
+)
+
+// lookupsCmd represents the lookups command
+var lookupsCmd = &cobra.Command{
+	Use:   "lookups",
+	Short: "write the PRTG value lookups used by the sensors",
+	Long: `writes a .ovl file for vSphere status, power state, tools status, tools running, connection state
+and maintenance mode to --dir
+
+copy them to the lookups\custom folder of the PRTG core server, I.E.
+C:\Program Files (x86)\PRTG Network Monitor\lookups\custom, then use
+Setup > System Administration > Administrative Tools > Load Lookups and File Lists
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := cmd.Flags().GetString("dir")
+		if err != nil {
+			return err
+		}
+		files, err := app.WriteLookups(dir)
+		for _, f := range files {
+			fmt.Println("saved", f)
+		}
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lookupsCmd)
+	lookupsCmd.Flags().StringP("dir", "d", ".", "folder to write the lookup files to")
+}
@@ -15,51 +15,57 @@
 package cmd
 
 import (
+	"context"
 	"github.com/mutl3y/prtgvmware/app"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
 )
 
 // metascanCmd represents the metascan command
 var metascanCmd = &cobra.Command{
 	Use:   "metascan",
 	Short: "returns prtg sensors for autodiscovery",
-	Long:  `used for autodiscovery of vmware sensors`,
-	PreRunE: func(cmd *cobra.Command, args []string) error {
-		err := rootCmd.MarkPersistentFlagRequired("tags")
-		return err
-	},
-	Run: func(cmd *cobra.Command, args []string) {
+	Long: `used for autodiscovery of vmware sensors
 
-		flags := cmd.Flags()
-		c, err := login(flags)
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
-		tags, err := flags.GetStringSlice("tags")
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
+objects are selected by --tags, which needs vcenter, or by --names and --folders
+which also work when connected directly to an ESXi host`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runSensor(cmd, metascan)
+	},
+}
 
-		snapAge, err := flags.GetDuration("snapAge")
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
-		tagMap := app.NewTagMap()
+func metascan(ctx context.Context, flags *pflag.FlagSet, w io.Writer) error {
+	c, err := login(ctx, flags)
+	if err != nil {
+		return err
+	}
+	c.SetOutput(w)
+	sel, err := selection(flags)
+	if err != nil {
+		return err
+	}
 
-		err = c.Metascan(tags, tagMap, snapAge)
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
+	snapAge, err := flags.GetDuration("snapAge")
+	if err != nil {
+		return err
+	}
+	profile, err := flags.GetString("profile")
+	if err != nil {
+		return err
+	}
+	tagMap := app.NewTagMap()
 
-		_ = c.Logout()
+	err = c.Metascan(ctx, sel, tagMap, snapAge, profile)
+	if err != nil {
+		return err
+	}
 
-	},
+	_ = c.Logout()
+	return nil
 }
 
 func init() {
 	rootCmd.AddCommand(metascanCmd)
+	registerSensor(metascanCmd, metascan)
 }
@@ -0,0 +1,128 @@
+/*Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/mutl3y/prtgvmware/app"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"log"
+	"net/http"
+	"time"
+)
+
+// pushCmd represents the push command
+var pushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "push summaries to PRTG HTTP Push Data Advanced sensors",
+	Long: `collects the summary of every object in the token file written by dynamicTemplates --push,
+or the objects selected by --tags, --names or --folders, and posts each result to the push sensor
+holding the object's token
+
+runs once unless --interval is given, objects without a token are reported and skipped
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flags := cmd.Flags()
+		fn, err := flags.GetString("tokens")
+		if err != nil {
+			return err
+		}
+		interval, err := flags.GetDuration("interval")
+		if err != nil {
+			return err
+		}
+		timeout, err := flags.GetDuration("timeout")
+		if err != nil {
+			return err
+		}
+		t, err := pushTokens(flags, fn)
+		if err != nil {
+			return err
+		}
+		if len(t.Objects) == 0 {
+			return fmt.Errorf("no tokens in %v, run dynamicTemplates --push %v first", fn, fn)
+		}
+		sel := t.Selection()
+		if flags.Changed("tags") || flags.Changed("names") || flags.Changed("folders") {
+			sel, err = selection(flags)
+			if err != nil {
+				return err
+			}
+		}
+		opts, err := collectOptions(flags)
+		if err != nil {
+			return err
+		}
+		limit, err := channelLimit(flags)
+		if err != nil {
+			return err
+		}
+		p := &app.Pusher{Tokens: t, Client: &http.Client{Timeout: 30 * time.Second}, Limit: limit}
+
+		return repeat(flags, interval, func(ctx context.Context) error {
+			return push(ctx, flags, p, sel, opts, timeout)
+		})
+	},
+}
+
+// push collects once and posts every result, failed posts are logged so one missing sensor doesn't stop the rest
+func push(ctx context.Context, flags *pflag.FlagSet, p *app.Pusher, sel app.Selection, opts app.CollectOptions, timeout time.Duration) error {
+	sent, failed := 0, 0
+	err := collect(ctx, flags, sel, opts, timeout, func(r app.Result) error {
+		err := p.Push(ctx, r)
+		if err != nil {
+			failed++
+			log.Println(err)
+			return nil
+		}
+		sent++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("pushed %v results, %v failed", sent, failed)
+	if failed > 0 {
+		return fmt.Errorf("%v of %v pushes failed", failed, sent+failed)
+	}
+	return nil
+}
+
+// pushTokens loads the token file, --pushUrl replaces the endpoint saved in it
+func pushTokens(flags *pflag.FlagSet, fn string) (*app.PushTokens, error) {
+	t, err := app.LoadPushTokens(fn)
+	if err != nil {
+		return nil, err
+	}
+	u, err := flags.GetString("pushUrl")
+	if err != nil {
+		return nil, err
+	}
+	if u != "" {
+		t.URL = u
+	}
+	return t, nil
+}
+
+func init() {
+	rootCmd.AddCommand(pushCmd)
+	pushCmd.Flags().String("tokens", "pushtokens.yml", "token file written by dynamicTemplates --push")
+	pushCmd.Flags().String("pushUrl", "", "probe push endpoint, overrides the url in the token file, I.E. http://probe:5050")
+	pushCmd.Flags().Duration("interval", 0, "push this often, 0 pushes once")
+	pushCmd.Flags().Int("workers", 4, "objects collected at once")
+	pushCmd.Flags().StringSlice("vmMetrics", []string{}, "include additional vm metrics, I.E. cpu.ready.summation")
+}
@@ -15,12 +15,17 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"github.com/mutl3y/prtgvmware/app"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"log"
 	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/template"
 	"time"
 )
 
@@ -35,6 +40,7 @@ this app exposes all the common stats for vm's, Hypervisor's, VDS & Datastore's
 
 to use autodiscovery you need to generate template using tags for each set of objects you want to monitor
 `,
+	PersistentPreRunE: initConfig,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -49,12 +55,13 @@ func Execute() {
 }
 
 func init() {
-	//cobra.OnInitialize(initConfig)
-	//rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "prtgvmware.yaml", "config file ")
-	//rootCmd.PersistentFlags().BoolP("saveconfig", "s", false, "save parameters to file")
+	rootCmd.PersistentFlags().String("config", "", "config file holding vcenter profiles, defaults to prtgvmware.yml in the cache folder")
+	rootCmd.PersistentFlags().StringP("profile", "P", "", "named vcenter profile from config file, flags override profile settings")
 
 	rootCmd.PersistentFlags().StringP("username", "u", "", "vcenter username")
-	rootCmd.PersistentFlags().StringP("password", "p", "", "vcenter password")
+	rootCmd.PersistentFlags().StringP("password", "p", "", "vcenter password, visible in process listings, prefer the other password sources")
+	rootCmd.PersistentFlags().String("password-file", "", "read the vcenter password from the first line of this file")
+	rootCmd.PersistentFlags().String("password-command", "", "run this command and use what it prints as the vcenter password")
 	rootCmd.PersistentFlags().StringP("url", "U", "", "url for vcenter api")
 	rootCmd.PersistentFlags().String("msgWarn", "", "message to use if warning value exceeded (used with snapshots)")
 	rootCmd.PersistentFlags().String("msgError", "", "message to use if error value exceeded (used with snapshots)")
@@ -66,38 +73,169 @@ func init() {
 	rootCmd.PersistentFlags().StringP("name", "n", "", "name of vm, supports *partofname*")
 	rootCmd.PersistentFlags().StringP("oid", "i", "", "exact id of an object e.g. vm-12, vds-81, host-9, datastore-10 ")
 	rootCmd.PersistentFlags().StringSliceP("tags", "t", []string{}, "slice of tags to include")
+	rootCmd.PersistentFlags().StringSlice("names", []string{}, "discover objects whose name matches these patterns, I.E web*, works without vcenter")
+	rootCmd.PersistentFlags().StringSlice("folders", []string{}, "discover everything below these inventory paths, I.E /ha-datacenter/vm, works without vcenter")
 	rootCmd.PersistentFlags().DurationP("snapAge", "a", (7*24)*time.Hour, "ignore snapshots younger than")
 	rootCmd.PersistentFlags().BoolP("json", "j", false, "pretty print json version of vmware data")
+	rootCmd.PersistentFlags().String("format", string(app.FormatJSON), "sensor output, prtg-json or prtg-xml for SSH Script Advanced sensors")
+	rootCmd.PersistentFlags().Int("page", 0, "report this block of 49 channels when a summary is over the PRTG limit of 50, templates add a sensor per page")
+	rootCmd.PersistentFlags().Bool("aggregate", false, "replace channels of the same kind, I.E one per portgroup, with their count, min, max and worst when over the PRTG limit")
+	rootCmd.PersistentFlags().Int("top", 0, "keep only the worst N channels of the same kind when over the PRTG limit")
+	rootCmd.PersistentFlags().String("channelNames", "", "yaml file of channel aliases and the longest channel name, keeps history when inventory names change")
+	rootCmd.PersistentFlags().String("message", "", "go text/template for the sensor message, I.E '{{.Name}} on {{.Host}}{{with .Worst}} {{.}}{{end}}', see README")
 	rootCmd.PersistentFlags().BoolP("cachedCreds", "c", false, "disable cached connection")
+	rootCmd.PersistentFlags().Duration("timeout", 50*time.Second, "sensors report an error when this is exceeded, keep it below the PRTG sensor timeout")
+	rootCmd.PersistentFlags().Duration("loginTimeout", 0, "budget for logging in, 0 is limited only by --timeout")
+	rootCmd.PersistentFlags().Duration("inventoryTimeout", 0, "budget for finding objects and reading their properties, 0 is limited only by --timeout")
+	rootCmd.PersistentFlags().Duration("metricsTimeout", 0, "budget for performance queries, 0 is limited only by --timeout")
+	rootCmd.PersistentFlags().String("ca-file", "", "PEM file of CA certificates used to verify vcenter, defaults to the system roots")
+	rootCmd.PersistentFlags().String("thumbprint", "", "pin the vcenter certificate by SHA1 or SHA256 thumbprint instead of verifying the CA")
+	rootCmd.PersistentFlags().Bool("insecure", false, "skip vcenter certificate verification")
+	rootCmd.PersistentFlags().String("record", "", "save every vcenter request and response to this folder, credentials and cookies are left out")
+	rootCmd.PersistentFlags().String("replay", "", "answer vcenter requests from a folder written by --record instead of contacting vcenter")
 
 }
 
-// initConfig reads in config file and ENV variables if set.
-//func initConfig() {
-//	if cfgFile != "" {
-//		// Use config file from the flag.
-//		viper.SetConfigFile(cfgFile)
-//	} else {
-//		// Find home directory.
-//		home, err := homedir.Dir()
-//		if err != nil {
-//			log.Fatal(err)
-//		}
-//
-//		// Search config in home directory with name "prtgvmware" (without extension).
-//		viper.AddConfigPath(home)
-//		viper.SetConfigType("yml")
-//		viper.SetConfigName("prtgvmware")
-//	}
-//
-//	viper.AutomaticEnv() // read in environment variables that match
-//
-//	// If a config file is found, read it in.
-//	if err := viper.ReadInConfig(); err == nil {
-//		fmt.Println("Using config file:", viper.ConfigFileUsed())
-//	}
-//
-//}
+// initConfig applies the selected config profile to any flags not set on the command line
+func initConfig(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+	cfgFile, err := flags.GetString("config")
+	if err != nil {
+		return err
+	}
+	profile, err := flags.GetString("profile")
+	if err != nil {
+		return err
+	}
+	if profile != "" {
+		if err := applyProfile(flags, cfgFile, profile); err != nil {
+			return err
+		}
+	}
+	err = resolveLogin(flags)
+	if err != nil {
+		return err
+	}
+	return recordReplay(flags)
+}
+
+// recordReplay sets up --record or --replay, both log in every time so the login is part of the recording
+func recordReplay(flags *pflag.FlagSet) error {
+	rec, err := flags.GetString("record")
+	if err != nil {
+		return err
+	}
+	rep, err := flags.GetString("replay")
+	if err != nil {
+		return err
+	}
+	switch {
+	case rec != "" && rep != "":
+		return fmt.Errorf("use either --record or --replay")
+	case rec != "":
+		r, err := app.NewRecorder(rec)
+		if err != nil {
+			return err
+		}
+		app.SetRecorder(r)
+	case rep != "":
+		r, err := app.LoadReplay(rep)
+		if err != nil {
+			return err
+		}
+		u, err := r.Start()
+		if err != nil {
+			return err
+		}
+		// the recording holds no credentials, any will do
+		for name, v := range map[string]string{"url": u.String(), "username": "replay", "password": "replay"} {
+			if err := flags.Set(name, v); err != nil {
+				return err
+			}
+		}
+	default:
+		return nil
+	}
+	return flags.Set("cachedCreds", "true")
+}
+
+// applyProfile sets flags not given on the command line from a config profile
+func applyProfile(flags *pflag.FlagSet, cfgFile, profile string) error {
+	if cfgFile == "" {
+		cfgFile = app.DefaultConfigFile()
+	}
+	if _, err := os.Stat(cfgFile); err != nil {
+		return fmt.Errorf("config file %v", err)
+	}
+	cfg, err := app.LoadConfig(cfgFile)
+	if err != nil {
+		return err
+	}
+	p, err := cfg.Profile(profile)
+	if err != nil {
+		return err
+	}
+
+	// a password source on the command line replaces any password source in the profile
+	pwFlags := []string{"password", "password-file", "password-command"}
+	pwGiven := false
+	for _, name := range pwFlags {
+		pwGiven = pwGiven || flags.Changed(name)
+	}
+	// values are set on the flag itself so Changed still only reports flags given on the command line
+	for name, value := range p.Flags() {
+		f := flags.Lookup(name)
+		if f == nil || f.Changed {
+			continue
+		}
+		if pwGiven && (name == pwFlags[0] || name == pwFlags[1] || name == pwFlags[2]) {
+			continue
+		}
+		if err := f.Value.Set(value); err != nil {
+			return fmt.Errorf("profile %v setting %v %v", profile, name, err)
+		}
+	}
+	return nil
+}
+
+// resolveLogin fills url, username and password from files, commands or the environment so they
+// never have to be on the command line, sensors pass the result on to the collector
+func resolveLogin(flags *pflag.FlagSet) error {
+	l := app.Login{}
+	for name, v := range map[string]*string{
+		"url":              &l.URL,
+		"username":         &l.Username,
+		"password":         &l.Password,
+		"password-file":    &l.PasswordFile,
+		"password-command": &l.PasswordCommand,
+	} {
+		s, err := flags.GetString(name)
+		if err != nil {
+			return err
+		}
+		*v = s
+	}
+	timeout, err := flags.GetDuration("timeout")
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	r, err := l.Resolve(ctx)
+	if err != nil {
+		return err
+	}
+
+	for name, v := range map[string]string{"url": r.URL, "username": r.Username, "password": r.Password} {
+		if v == "" || v == flags.Lookup(name).Value.String() {
+			continue
+		}
+		if err := flags.Set(name, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
 var (
 	warnMsg string
@@ -145,7 +283,7 @@ func limitStruct(flags *pflag.FlagSet) (lim app.LimitsStruct, err error) {
 	return lim, nil
 }
 
-func login(flags *pflag.FlagSet) (c app.Client, err error) {
+func login(ctx context.Context, flags *pflag.FlagSet) (c app.Client, err error) {
 	u := &url.URL{}
 	urls, err := flags.GetString("url")
 	if err != nil {
@@ -164,10 +302,201 @@ func login(flags *pflag.FlagSet) (c app.Client, err error) {
 	if err != nil {
 		return
 	}
+	tlsOpts, err := tlsOptions(flags)
+	if err != nil {
+		return
+	}
+	t, err := timeouts(flags)
+	if err != nil {
+		return
+	}
+	format, err := outputFormat(flags)
+	if err != nil {
+		return
+	}
+	limit, err := channelLimit(flags)
+	if err != nil {
+		return
+	}
+	names, err := channelNames(flags)
+	if err != nil {
+		return
+	}
+	message, err := messageTemplate(flags)
+	if err != nil {
+		return
+	}
 	u, _ = u.Parse(urls)
-	c, err = app.NewClient(u, user, pww, !useCached)
+
+	lctx, cancel := app.WithBudget(ctx, t.Login)
+	defer cancel()
+	if pool != nil {
+		c, err = pool.Get(lctx, u, user, pww, tlsOpts)
+	} else {
+		c, err = app.NewClient(lctx, u, user, pww, !useCached, tlsOpts)
+	}
 	if err != nil {
 		return
 	}
+	c.SetTimeouts(t)
+	c.SetFormat(format)
+	c.SetChannelLimit(limit)
+	c.SetChannelNames(names)
+	c.SetMessage(message)
+	return
+}
+
+func channelNames(flags *pflag.FlagSet) (*app.ChannelNames, error) {
+	fn, err := flags.GetString("channelNames")
+	if err != nil || fn == "" {
+		return nil, err
+	}
+	return app.LoadChannelNames(fn)
+}
+
+func messageTemplate(flags *pflag.FlagSet) (*template.Template, error) {
+	s, err := flags.GetString("message")
+	if err != nil || s == "" {
+		return nil, err
+	}
+	return app.ParseMessage(s)
+}
+
+func channelLimit(flags *pflag.FlagSet) (l app.ChannelLimit, err error) {
+	l.Page, err = flags.GetInt("page")
+	if err != nil {
+		return
+	}
+	l.Aggregate, err = flags.GetBool("aggregate")
+	if err != nil {
+		return
+	}
+	l.Top, err = flags.GetInt("top")
+	if err != nil {
+		return
+	}
+	return l, l.Validate()
+}
+
+func outputFormat(flags *pflag.FlagSet) (app.Format, error) {
+	f, err := flags.GetString("format")
+	if err != nil {
+		return "", err
+	}
+	return app.ParseFormat(f)
+}
+
+func timeouts(flags *pflag.FlagSet) (t app.Timeouts, err error) {
+	t.Login, err = flags.GetDuration("loginTimeout")
+	if err != nil {
+		return
+	}
+	t.Inventory, err = flags.GetDuration("inventoryTimeout")
+	if err != nil {
+		return
+	}
+	t.Metrics, err = flags.GetDuration("metricsTimeout")
+	return
+}
+
+// commandCtx bounds commands that aren't sensors, --timeout only applies to them when set explicitly
+func commandCtx(flags *pflag.FlagSet) (context.Context, context.CancelFunc) {
+	timeout, err := flags.GetDuration("timeout")
+	if err != nil || !flags.Changed("timeout") {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// repeat runs f once, or every interval until interrupted, errors of repeated runs are logged and the next run still happens
+func repeat(flags *pflag.FlagSet, interval time.Duration, f func(ctx context.Context) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	go func() {
+		select {
+		case <-sig:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if interval <= 0 {
+		return f(ctx)
+	}
+	noCache, err := flags.GetBool("cachedCreds")
+	if err != nil {
+		return err
+	}
+	pool = app.NewPool(!noCache)
+	defer pool.Close()
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		err = f(ctx)
+		if err != nil {
+			log.Println(err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick.C:
+		}
+	}
+}
+
+// collect logs in and hands the result of every selected object to f, timeout bounds the whole collection
+func collect(ctx context.Context, flags *pflag.FlagSet, sel app.Selection, opts app.CollectOptions, timeout time.Duration, f func(app.Result) error) error {
+	ctx, cancel := app.WithBudget(ctx, timeout)
+	defer cancel()
+	c, err := login(ctx, flags)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if !c.Cached {
+			_ = c.Logout()
+		}
+	}()
+	return c.Collect(ctx, sel, opts, f)
+}
+
+func tlsOptions(flags *pflag.FlagSet) (t app.TLSOptions, err error) {
+	t.Insecure, err = flags.GetBool("insecure")
+	if err != nil {
+		return
+	}
+	t.CAFile, err = flags.GetString("ca-file")
+	if err != nil {
+		return
+	}
+	t.Thumbprint, err = flags.GetString("thumbprint")
+	if err != nil {
+		return
+	}
+	if t.Insecure && (t.CAFile != "" || t.Thumbprint != "") {
+		return t, fmt.Errorf("--insecure can't be combined with --ca-file or --thumbprint")
+	}
+	return
+}
+
+func selection(flags *pflag.FlagSet) (sel app.Selection, err error) {
+	sel.Tags, err = flags.GetStringSlice("tags")
+	if err != nil {
+		return
+	}
+	sel.Names, err = flags.GetStringSlice("names")
+	if err != nil {
+		return
+	}
+	sel.Folders, err = flags.GetStringSlice("folders")
+	if err != nil {
+		return
+	}
+	if sel.Empty() {
+		return sel, fmt.Errorf("provide --tags, --names or --folders")
+	}
 	return
 }
@@ -0,0 +1,419 @@
+/*Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mutl3y/prtgvmware/app"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// sensorFunc runs a sensor using parsed flags, writing PRTG output to w
+type sensorFunc func(ctx context.Context, flags *pflag.FlagSet, w io.Writer) error
+
+type sensorCmd struct {
+	cmd *cobra.Command
+	run sensorFunc
+}
+
+// sensors that can be answered by the collector started with serve
+var sensors = make(map[string]sensorCmd)
+
+// pool is set when running as a collector so logins are shared between requests
+var pool *app.Pool
+
+const (
+	dialTimeout    = 2 * time.Second
+	requestTimeout = 5 * time.Minute
+	// time allowed after the deadline for calls to report which phase ran out
+	deadlineGrace = 2 * time.Second
+)
+
+type serveRequest struct {
+	Cmd   string              `json:"cmd"`
+	Flags map[string][]string `json:"flags"`
+}
+
+type serveResponse struct {
+	Output string `json:"output"`
+	Err    string `json:"err,omitempty"`
+}
+
+func registerSensor(cmd *cobra.Command, f sensorFunc) {
+	sensors[cmd.Name()] = sensorCmd{cmd: cmd, run: f}
+}
+
+// runSensor hands the request to a running collector, falling back to querying vcenter directly
+func runSensor(cmd *cobra.Command, f sensorFunc) {
+	flags := cmd.Flags()
+	format, err := outputFormat(flags)
+	if err != nil {
+		app.SensorWarn(err, true)
+		return
+	}
+	timeout, err := flags.GetDuration("timeout")
+	if err != nil {
+		app.SensorWarnTo(os.Stdout, format, err, true)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	out, err := forward(ctx, cmd.Name(), flags)
+	if err == nil {
+		fmt.Print(out)
+		return
+	}
+	if ctx.Err() != nil {
+		app.SensorWarnTo(os.Stdout, format, fmt.Errorf("collector did not answer within --timeout %v", timeout), true)
+		return
+	}
+
+	out, err = runWithDeadline(ctx, flags, f)
+	if err != nil {
+		app.SensorWarnTo(os.Stdout, format, err, true)
+		return
+	}
+	fmt.Print(out)
+}
+
+// runWithDeadline returns an error if f overruns ctx, so PRTG always gets a result before it kills the sensor
+func runWithDeadline(ctx context.Context, flags *pflag.FlagSet, f sensorFunc) (string, error) {
+	type result struct {
+		out string
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		buf := &bytes.Buffer{}
+		err := f(ctx, flags, buf)
+		done <- result{out: buf.String(), err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.out, r.err
+	case <-ctx.Done():
+	}
+	select {
+	case r := <-done:
+		return r.out, r.err
+	case <-time.After(deadlineGrace):
+		return "", fmt.Errorf("sensor did not finish within --timeout")
+	}
+}
+
+// forward sends the command and every flag that was set to the collector
+func forward(ctx context.Context, name string, flags *pflag.FlagSet) (out string, err error) {
+	direct, err := flags.GetBool("direct")
+	if err != nil || direct {
+		return "", fmt.Errorf("direct mode")
+	}
+	socket, err := flags.GetString("socket")
+	if err != nil {
+		return
+	}
+	if _, err = os.Stat(socket); err != nil {
+		return
+	}
+
+	req := serveRequest{Cmd: name, Flags: make(map[string][]string)}
+	flags.Visit(func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			req.Flags[f.Name] = sv.GetSlice()
+			return
+		}
+		req.Flags[f.Name] = []string{f.Value.String()}
+	})
+
+	conn, err := net.DialTimeout("unix", socket, dialTimeout)
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(requestTimeout)
+	}
+	_ = conn.SetDeadline(deadline.Add(deadlineGrace))
+
+	err = json.NewEncoder(conn).Encode(req)
+	if err != nil {
+		return
+	}
+	resp := serveResponse{}
+	err = json.NewDecoder(conn).Decode(&resp)
+	if err != nil {
+		return
+	}
+	if resp.Err != "" {
+		return "", fmt.Errorf("%v", resp.Err)
+	}
+	return resp.Output, nil
+}
+
+// collector answers sensor requests using pooled sessions
+type collector struct {
+	specs   map[string][]*pflag.Flag
+	workers chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newCollector(workers int) (*collector, error) {
+	d := &collector{
+		specs:   make(map[string][]*pflag.Flag),
+		workers: make(chan struct{}, workers),
+	}
+	// flag definitions are captured up front, requests never touch the shared cobra flags
+	for name, s := range sensors {
+		spec := make([]*pflag.Flag, 0, 20)
+		add := func(f *pflag.Flag) { spec = append(spec, f) }
+		s.cmd.InheritedFlags().VisitAll(add)
+		s.cmd.LocalFlags().VisitAll(add)
+		if _, err := flagSet(name, spec, nil); err != nil {
+			return nil, err
+		}
+		d.specs[name] = spec
+	}
+	return d, nil
+}
+
+// flagSet builds a fresh flag set from a captured spec and applies request values
+func flagSet(name string, spec []*pflag.Flag, values map[string][]string) (*pflag.FlagSet, error) {
+	fs := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	for _, f := range spec {
+		if fs.Lookup(f.Name) != nil {
+			continue
+		}
+		def := f.DefValue
+		switch f.Value.Type() {
+		case "string":
+			fs.StringP(f.Name, f.Shorthand, def, f.Usage)
+		case "bool":
+			v, _ := strconv.ParseBool(def)
+			fs.BoolP(f.Name, f.Shorthand, v, f.Usage)
+		case "int":
+			v, _ := strconv.Atoi(def)
+			fs.IntP(f.Name, f.Shorthand, v, f.Usage)
+		case "float64":
+			v, _ := strconv.ParseFloat(def, 64)
+			fs.Float64P(f.Name, f.Shorthand, v, f.Usage)
+		case "duration":
+			v, _ := time.ParseDuration(def)
+			fs.DurationP(f.Name, f.Shorthand, v, f.Usage)
+		case "stringSlice":
+			var v []string
+			if d := strings.Trim(def, "[]"); d != "" {
+				v = strings.Split(d, ",")
+			}
+			fs.StringSliceP(f.Name, f.Shorthand, v, f.Usage)
+		default:
+			return nil, fmt.Errorf("%v flag %v has unsupported type %v", name, f.Name, f.Value.Type())
+		}
+	}
+
+	for k, v := range values {
+		f := fs.Lookup(k)
+		if f == nil {
+			return nil, fmt.Errorf("unknown flag %v", k)
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			if err := sv.Replace(v); err != nil {
+				return nil, err
+			}
+			f.Changed = true
+			continue
+		}
+		if len(v) != 1 {
+			return nil, fmt.Errorf("flag %v expects a single value", k)
+		}
+		if err := fs.Set(k, v[0]); err != nil {
+			return nil, err
+		}
+	}
+	return fs, nil
+}
+
+func (d *collector) handle(conn net.Conn) {
+	defer d.wg.Done()
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	d.workers <- struct{}{}
+	defer func() { <-d.workers }()
+
+	resp := serveResponse{}
+	req := serveRequest{}
+	err := json.NewDecoder(conn).Decode(&req)
+	if err != nil {
+		resp.Err = fmt.Sprintf("bad request %v", err)
+		_ = json.NewEncoder(conn).Encode(resp)
+		return
+	}
+
+	s, ok := sensors[req.Cmd]
+	if !ok {
+		resp.Err = fmt.Sprintf("unsupported command %v", req.Cmd)
+		_ = json.NewEncoder(conn).Encode(resp)
+		return
+	}
+	flags, err := flagSet(req.Cmd, d.specs[req.Cmd], req.Flags)
+	if err != nil {
+		resp.Err = err.Error()
+		_ = json.NewEncoder(conn).Encode(resp)
+		return
+	}
+
+	timeout, err := flags.GetDuration("timeout")
+	if err != nil {
+		resp.Err = err.Error()
+		_ = json.NewEncoder(conn).Encode(resp)
+		return
+	}
+	format, err := outputFormat(flags)
+	if err != nil {
+		resp.Err = err.Error()
+		_ = json.NewEncoder(conn).Encode(resp)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	out, err := runWithDeadline(ctx, flags, s.run)
+	if err != nil {
+		buf := &bytes.Buffer{}
+		app.SensorWarnTo(buf, format, err, true)
+		out = buf.String()
+	}
+	resp.Output = out
+	_ = json.NewEncoder(conn).Encode(resp)
+}
+
+// listen removes a socket left behind by a collector that is no longer running
+func listen(socket string) (net.Listener, error) {
+	if _, err := os.Stat(socket); err == nil {
+		conn, err := net.DialTimeout("unix", socket, dialTimeout)
+		if err == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("collector already listening on %v", socket)
+		}
+		_ = os.Remove(socket)
+	}
+	// requests carry passwords, only the account running the collector may connect,
+	// the umask covers the socket from the moment it is created, the chmod is kept for platforms ignoring it
+	restore := privateUmask()
+	l, err := net.Listen("unix", socket)
+	restore()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(socket, 0600); err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "run a collector that answers sensor requests",
+	Long: `keeps one logged in session per vcenter and user, and caches counter metadata and object lookups
+
+while it is running summary, hsSummary, dsSummary, vdsSummary, clusterSummary, rpSummary, podSummary, pgSummary, hwHealth, hostCompliance, snapshots and metascan
+hand their request to the collector over a local socket, if it is not running they
+query vcenter directly, use --direct to always bypass the collector
+
+run this as the same account that PRTG uses for EXE sensors, named pipes are not supported,
+on windows the socket needs Windows 10 1803 / Server 2019 or later
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flags := cmd.Flags()
+		socket, err := flags.GetString("socket")
+		if err != nil {
+			return err
+		}
+		workers, err := flags.GetInt("workers")
+		if err != nil {
+			return err
+		}
+		noCache, err := flags.GetBool("cachedCreds")
+		if err != nil {
+			return err
+		}
+		if workers < 1 {
+			return fmt.Errorf("workers must be at least 1")
+		}
+
+		d, err := newCollector(workers)
+		if err != nil {
+			return err
+		}
+		err = os.MkdirAll(filepath.Dir(socket), 0700)
+		if err != nil {
+			return err
+		}
+		l, err := listen(socket)
+		if err != nil {
+			return err
+		}
+
+		pool = app.NewPool(!noCache)
+		defer pool.Close()
+
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sig
+			_ = l.Close()
+		}()
+
+		log.Printf("collector listening on %v", socket)
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				break
+			}
+			d.wg.Add(1)
+			go d.handle(conn)
+		}
+		d.wg.Wait()
+		_ = os.Remove(socket)
+		log.Printf("collector stopped")
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().Int("workers", 16, "maximum number of requests answered at once")
+	rootCmd.PersistentFlags().String("socket", app.DefaultSocketFile(), "socket used to reach the collector started with serve")
+	rootCmd.PersistentFlags().Bool("direct", false, "query vcenter directly even if a collector is running")
+}
@@ -15,10 +15,12 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
-	"github.com/mutl3y/prtgvmware/app"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/vmware/govmomi/property"
+	"io"
 )
 
 // snapshotsCmd represents the snapshots command
@@ -28,57 +30,56 @@ var snapshotsCmd = &cobra.Command{
 	Long: `queries a count of snapshot's that are older than specified
 snapAge is 7 days by default`,
 	Run: func(cmd *cobra.Command, args []string) {
-		flags := cmd.Flags()
-		c, err := login(flags)
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
-		f := property.Filter{}
-		name, err := flags.GetString("name")
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
-		tags, err := flags.GetStringSlice("tags")
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
-		age, err := flags.GetDuration("snapAge")
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
+		runSensor(cmd, snapshots)
+	},
+}
 
-		js, err := flags.GetBool("json")
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
-		if name != "" {
-			f["name"] = name
-		} else {
-			f["name"] = "*"
-		}
+func snapshots(ctx context.Context, flags *pflag.FlagSet, w io.Writer) error {
+	c, err := login(ctx, flags)
+	if err != nil {
+		return err
+	}
+	c.SetOutput(w)
+	f := property.Filter{}
+	name, err := flags.GetString("name")
+	if err != nil {
+		return err
+	}
+	tags, err := flags.GetStringSlice("tags")
+	if err != nil {
+		return err
+	}
+	age, err := flags.GetDuration("snapAge")
+	if err != nil {
+		return err
+	}
 
-		lim, err := limitStruct(flags)
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
+	js, err := flags.GetBool("json")
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		f["name"] = name
+	} else {
+		f["name"] = "*"
+	}
 
-		err = c.SnapShotsOlderThan(f, tags, &lim, age, js)
-		if err != nil {
-			app.SensorWarn(fmt.Errorf("get snapshots error: %v", err), true)
-			return
-		}
-		if !c.Cached {
-			_ = c.Logout()
-		}
-	},
+	lim, err := limitStruct(flags)
+	if err != nil {
+		return err
+	}
+
+	err = c.SnapShotsOlderThan(ctx, f, tags, &lim, age, js)
+	if err != nil {
+		return fmt.Errorf("get snapshots error: %v", err)
+	}
+	if !c.Cached {
+		_ = c.Logout()
+	}
+	return nil
 }
 
 func init() {
 	rootCmd.AddCommand(snapshotsCmd)
+	registerSensor(snapshotsCmd, snapshots)
 }
@@ -15,9 +15,11 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
-	"github.com/mutl3y/prtgvmware/app"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
 )
 
 // summaryCmd represents the summary command
@@ -37,63 +39,56 @@ counters included by default are
 "net.bytesRx.average", "net.bytesTx.average", "net.usage.average",
 `,
 	Run: func(cmd *cobra.Command, args []string) {
-		flags := cmd.Flags()
-		c, err := login(flags)
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
-		name, err := flags.GetString("name")
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
-		oid, err := flags.GetString("oid")
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
+		runSensor(cmd, vmSummary)
+	},
+}
 
-		if name == "" && oid == "" {
-			app.SensorWarn(fmt.Errorf("you need to provide a name or managed object id"), true)
-			return
-		}
+func vmSummary(ctx context.Context, flags *pflag.FlagSet, w io.Writer) error {
+	c, err := login(ctx, flags)
+	if err != nil {
+		return err
+	}
+	c.SetOutput(w)
+	name, err := flags.GetString("name")
+	if err != nil {
+		return err
+	}
+	oid, err := flags.GetString("oid")
+	if err != nil {
+		return err
+	}
 
-		snapAge, err := flags.GetDuration("snapAge")
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
+	if name == "" && oid == "" {
+		return fmt.Errorf("you need to provide a name or managed object id")
+	}
 
-		lim, err := limitStruct(flags)
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
+	snapAge, err := flags.GetDuration("snapAge")
+	if err != nil {
+		return err
+	}
 
-		extraSensors, err := flags.GetStringSlice("vmMetrics")
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
-		js, err := flags.GetBool("json")
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
+	lim, err := limitStruct(flags)
+	if err != nil {
+		return err
+	}
 
-		err = c.VMSummary(name, oid, &lim, snapAge, js, extraSensors)
-		if err != nil {
-			app.SensorWarn(err, true)
+	extraSensors, err := flags.GetStringSlice("vmMetrics")
+	if err != nil {
+		return err
+	}
+	js, err := flags.GetBool("json")
+	if err != nil {
+		return err
+	}
 
-		}
-		//if !c.Cached {
-		//	c.Logout()
-		//}
-	},
+	return c.VMSummary(ctx, name, oid, &lim, snapAge, js, extraSensors)
+	//if !c.Cached {
+	//	c.Logout()
+	//}
 }
 
 func init() {
 	rootCmd.AddCommand(summaryCmd)
+	registerSensor(summaryCmd, vmSummary)
 	summaryCmd.Flags().StringSlice("vmMetrics", []string{}, "include additional vm metrics, I.E. cpu.ready.summation")
 }
@@ -31,14 +31,10 @@ discovery
 if you wish to use this option you will need to delete all your sensors 
 from the device for the metascan to find anything new, in a very stable environment this can work
 `,
-	PreRunE: func(cmd *cobra.Command, args []string) error {
-		err := rootCmd.MarkPersistentFlagRequired("tags")
-		return err
-	},
 	Run: func(cmd *cobra.Command, args []string) {
 
 		flags := cmd.Flags()
-		tags, err := flags.GetStringSlice("tags")
+		sel, err := selection(flags)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -50,8 +46,12 @@ from the device for the metascan to find anything new, in a very stable environm
 		if err != nil {
 			log.Fatal(err)
 		}
+		profile, err := flags.GetString("profile")
+		if err != nil {
+			log.Fatal(err)
+		}
 
-		err = app.GenTemplate(tags, snapAge, tplate)
+		err = app.GenTemplate(sel, snapAge, tplate, profile)
 		if err != nil {
 			log.Fatal(err)
 		}
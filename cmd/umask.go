@@ -0,0 +1,27 @@
+//go:build !windows
+// +build !windows
+
+/*Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cmd
+
+import "syscall"
+
+// privateUmask makes new files only accessible to the account running the collector,
+// the returned func restores the previous umask
+func privateUmask() func() {
+	old := syscall.Umask(0077)
+	return func() { syscall.Umask(old) }
+}
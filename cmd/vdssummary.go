@@ -15,8 +15,10 @@
 package cmd
 
 import (
-	"github.com/mutl3y/prtgvmware/app"
+	"context"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
 )
 
 var vdsSummaryCmd = &cobra.Command{
@@ -24,38 +26,37 @@ var vdsSummaryCmd = &cobra.Command{
 	Short: "vds summary for prtg",
 	Long:  `Provides basic vds status for PRTG monitoring`,
 	Run: func(cmd *cobra.Command, args []string) {
-		flags := cmd.Flags()
-		c, err := login(flags)
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
-		oid, err := flags.GetString("oid")
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
+		runSensor(cmd, vdsSummary)
+	},
+}
 
-		name, err := flags.GetString("name")
-		if err != nil {
-			app.SensorWarn(err, true)
-		}
-		js, err := flags.GetBool("json")
-		if err != nil {
-			app.SensorWarn(err, true)
-			return
-		}
-		err = c.VdsSummary(name, oid, js)
-		if err != nil {
-			app.SensorWarn(err, true)
+func vdsSummary(ctx context.Context, flags *pflag.FlagSet, w io.Writer) error {
+	c, err := login(ctx, flags)
+	if err != nil {
+		return err
+	}
+	c.SetOutput(w)
+	oid, err := flags.GetString("oid")
+	if err != nil {
+		return err
+	}
 
-		}
-		if !c.Cached {
-			_ = c.Logout()
-		}
-	},
+	name, err := flags.GetString("name")
+	if err != nil {
+		return err
+	}
+	js, err := flags.GetBool("json")
+	if err != nil {
+		return err
+	}
+	err = c.VdsSummary(ctx, name, oid, js)
+	if !c.Cached {
+		_ = c.Logout()
+	}
+	return err
 }
 
 func init() {
 	rootCmd.AddCommand(vdsSummaryCmd)
+	registerSensor(vdsSummaryCmd, vdsSummary)
 }
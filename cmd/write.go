@@ -0,0 +1,116 @@
+/*Copyright © 2019.  mutl3y
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/mutl3y/prtgvmware/app"
+	"github.com/spf13/cobra"
+	"net/url"
+	"time"
+)
+
+// writeCmd represents the write command
+var writeCmd = &cobra.Command{
+	Use:   "write",
+	Short: "write summaries as influxdb line protocol or graphite plaintext",
+	Long: `collects the summary of every host, datastore, distributed switch and vm selected by
+--tags, --names or --folders and writes them for long term storage in influxdb or graphite
+
+--output is - for stdout, a file path, tcp://host:port, udp://host:port or an http(s) url such as
+the influx write api, I.E. http://influx:8086/write?db=vmware
+
+every value is tagged with vcenter, type, moid, name, the vSphere tags, name patterns or folders that
+selected the object and instance, which tells guest disks, port groups and per instance counters apart
+
+runs once unless --interval is given
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		flags := cmd.Flags()
+		lf, err := flags.GetString("lineFormat")
+		if err != nil {
+			return err
+		}
+		format, err := app.ParseLineFormat(lf)
+		if err != nil {
+			return err
+		}
+		dest, err := flags.GetString("output")
+		if err != nil {
+			return err
+		}
+		prefix, err := flags.GetString("prefix")
+		if err != nil {
+			return err
+		}
+		interval, err := flags.GetDuration("interval")
+		if err != nil {
+			return err
+		}
+		timeout, err := flags.GetDuration("timeout")
+		if err != nil {
+			return err
+		}
+		urls, err := flags.GetString("url")
+		if err != nil {
+			return err
+		}
+		u, err := url.Parse(urls)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid --url %v", urls)
+		}
+		sel, err := selection(flags)
+		if err != nil {
+			return err
+		}
+		opts, err := collectOptions(flags)
+		if err != nil {
+			return err
+		}
+
+		out, err := app.OpenOutput(dest, 30*time.Second)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = out.Close() }()
+		enc := app.LineEncoder{Format: format, Prefix: prefix, VCenter: u.Hostname()}
+
+		return repeat(flags, interval, func(ctx context.Context) error {
+			b := &bytes.Buffer{}
+			err := collect(ctx, flags, sel, opts, timeout, func(r app.Result) error {
+				return enc.Encode(b, r)
+			})
+			// whatever was collected before a failure is still worth keeping
+			if b.Len() > 0 {
+				if serr := out.Send(b.Bytes()); serr != nil && err == nil {
+					err = serr
+				}
+			}
+			return err
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(writeCmd)
+	writeCmd.Flags().String("lineFormat", string(app.LineInflux), "influx for the influxdb line protocol or graphite for graphite plaintext")
+	writeCmd.Flags().StringP("output", "o", "-", "where to write, -, a file, tcp://host:port, udp://host:port or an http(s) url")
+	writeCmd.Flags().String("prefix", "vmware", "start of every influx measurement or graphite metric path")
+	writeCmd.Flags().Duration("interval", 0, "write this often, 0 writes once")
+	writeCmd.Flags().Int("workers", 4, "objects collected at once")
+	writeCmd.Flags().StringSlice("vmMetrics", []string{}, "include additional vm metrics, I.E. cpu.ready.summation")
+}